@@ -1,3 +1,3 @@
 module github.com/aisbergg/go-retry
 
-go 1.16
+go 1.21
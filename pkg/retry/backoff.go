@@ -1,17 +1,83 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
 	"math/rand"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// deterministic is set to 1 to disable randomness. See SetDeterministic.
+var deterministic uint32
+
+// SetDeterministic enables or disables the deterministic mode. In
+// deterministic mode, all jitter middleware (e.g. WithJitter or
+// WithJitterPercent) return the center of their random range instead of a
+// random value. It is intended for tests of code using jittered backoffs only.
+// The mode is process-global and affects all backoffs.
+func SetDeterministic(on bool) {
+	var v uint32
+	if on {
+		v = 1
+	}
+	atomic.StoreUint32(&deterministic, v)
+}
+
+// randInt63n returns a random number in [0, n). In deterministic mode it
+// returns the center n/2 instead. It returns 0 if n <= 0.
+func randInt63n(n int64) int64 {
+	if n <= 0 {
+		return 0
+	}
+	if atomic.LoadUint32(&deterministic) == 1 {
+		return n / 2
+	}
+	return rand.Int63n(n)
+}
+
+// randFloat64 returns a random number in [0, 1). In deterministic mode it
+// returns the center 0.5 instead.
+func randFloat64() float64 {
+	if atomic.LoadUint32(&deterministic) == 1 {
+		return 0.5
+	}
+	return rand.Float64()
+}
+
+// randSource is a random source, that is safe for concurrent use. A nil
+// *randSource uses the global source of math/rand.
+type randSource struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// newRandSource returns a random source guarded by a mutex, since *rand.Rand
+// is not safe for concurrent use. Panics if r is nil.
+func newRandSource(r *rand.Rand) *randSource {
+	if r == nil {
+		panic("random source must not be nil")
+	}
+	return &randSource{r: r}
+}
+
+// int63n is like randInt63n, but draws from the source.
+func (s *randSource) int63n(n int64) int64 {
+	if s == nil || n <= 0 || atomic.LoadUint32(&deterministic) == 1 {
+		return randInt63n(n)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Int63n(n)
 }
 
-// Backoff is an interface that backs off.
+// Backoff is an interface that backs off. The built-in implementations are
+// safe for concurrent use (see the package documentation).
 type Backoff interface {
 	// Next takes the error and returns the time duration to wait and the
 	// processed error. A duration less than zero signals the backoff to stop
@@ -27,6 +93,185 @@ func (b BackoffFunc) Next(err error) (time.Duration, error) {
 	return b(err)
 }
 
+// ContextBackoff is implemented by backoffs that take the context of the
+// retried operation into account, e.g. to keep separate state per request or
+// tenant, or to shorten the delay when the deadline of the context is near. Do
+// passes its context to NextContext instead of calling Next.
+type ContextBackoff interface {
+	Backoff
+
+	// NextContext is like Next, but additionally takes the context of the
+	// retried operation.
+	NextContext(ctx context.Context, err error) (time.Duration, error)
+}
+
+// AsContextBackoff returns b as a ContextBackoff. If b does not implement
+// ContextBackoff, it is wrapped by an adapter, whose NextContext ignores the
+// context and calls b.Next. The adapter forwards Peek and Reset to b.
+func AsContextBackoff(b Backoff) ContextBackoff {
+	if cb, ok := b.(ContextBackoff); ok {
+		return cb
+	}
+	return wrap("AsContextBackoff", "", b, func(ctx context.Context, err error) (time.Duration, error) {
+		return nextContext(ctx, b, err)
+	})
+}
+
+// nextContext calls b.NextContext if b implements ContextBackoff and b.Next
+// otherwise. When peeking, it calls b.Peek instead.
+func nextContext(ctx context.Context, b Backoff, err error) (time.Duration, error) {
+	if isPeek(ctx) {
+		if p, ok := b.(Peeker); ok {
+			return p.Peek(err)
+		}
+		return Stop, errPeekUnsupported
+	}
+	if cb, ok := b.(ContextBackoff); ok {
+		return cb.NextContext(ctx, err)
+	}
+	return b.Next(err)
+}
+
+// Peeker is implemented by backoffs that are able to tell their next delay
+// without advancing their state. See Peek.
+type Peeker interface {
+	// Peek returns the delay and the processed error that Next would return for
+	// the given error, without changing the state of the backoff.
+	Peek(err error) (time.Duration, error)
+}
+
+// errPeekUnsupported is returned when peeking a backoff that does not
+// implement Peeker.
+var errPeekUnsupported = errors.New("peek not supported")
+
+// peekContext is passed to the middleware when peeking. The middleware must
+// not change its state then.
+var peekContext = context.WithValue(context.Background(), peekContextKey{}, true)
+
+type peekContextKey struct{}
+
+// isPeek reports whether the context is the one used for peeking.
+func isPeek(ctx context.Context) bool {
+	return ctx == peekContext
+}
+
+// Peek returns the delay the backoff would return for the given error,
+// without advancing its state, e.g. to preview the time until the next retry.
+// A stop is reported as Stop, like with Next.
+//
+// Peeking is supported by the built-in generators and middleware. It returns
+// false if the backoff, or any backoff it wraps, does not support peeking
+// (e.g. a BackoffFunc), or if the next delay depends on a state change that
+// cannot be previewed (e.g. WithResetOnErrorChange resetting its backoff).
+// For randomized backoffs, such as those with jitter, the returned delay is a
+// sample; the actual delay is likely to differ.
+func Peek(b Backoff, err error) (time.Duration, bool) {
+	delay, err := nextContext(peekContext, b, err)
+	if errors.Is(err, errPeekUnsupported) {
+		return 0, false
+	}
+	return delay, true
+}
+
+// Factory creates a new, independent instance of a backoff. It is used
+// wherever a backoff with separate state is required, e.g. per key.
+type Factory func() Backoff
+
+// Resettable is implemented by stateful backoffs that can be reset to their
+// initial state. The built-in middleware implements it as well: it resets its
+// own state, if any, and forwards the call to the wrapped backoff. This way, a
+// whole chain can be reset and reused for independent operations instead of
+// composing it anew.
+type Resettable interface {
+	// Reset resets the backoff to its initial state.
+	Reset()
+}
+
+// Wrapper is implemented by backoffs that wrap another backoff (middleware).
+// It allows to walk a composed chain of backoffs.
+type Wrapper interface {
+	// Inner returns the wrapped backoff.
+	Inner() Backoff
+}
+
+// middleware is a backoff that wraps another backoff. In contrast to a plain
+// BackoffFunc it exposes the wrapped backoff for introspection.
+type middleware struct {
+	name string
+	args string
+	next Backoff
+	fn   func(ctx context.Context, err error) (time.Duration, error)
+
+	// bound estimates the worst-case behavior based on the one of the wrapped
+	// backoff. If nil, the middleware does not change it.
+	bound func(inner bounds) bounds
+
+	// reset resets the state of the middleware. It is nil for stateless
+	// middleware.
+	reset func()
+}
+
+// wrap creates a new middleware around next. The name and the formatted args
+// are used to describe the middleware.
+func wrap(name, args string, next Backoff, fn func(ctx context.Context, err error) (time.Duration, error)) *middleware {
+	return &middleware{
+		name: name,
+		args: args,
+		next: next,
+		fn:   fn,
+	}
+}
+
+// Next implements Backoff.
+func (m *middleware) Next(err error) (time.Duration, error) {
+	return m.fn(context.Background(), err)
+}
+
+// Peek implements Peeker.
+func (m *middleware) Peek(err error) (time.Duration, error) {
+	return m.fn(peekContext, err)
+}
+
+// NextContext implements ContextBackoff.
+func (m *middleware) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	return m.fn(ctx, err)
+}
+
+// Inner implements Wrapper.
+func (m *middleware) Inner() Backoff {
+	return m.next
+}
+
+// base returns the middleware itself. Through embedding, it gives access to the
+// middleware of the types built on top of it.
+func (m *middleware) base() *middleware {
+	return m
+}
+
+// Reset implements Resettable. It resets the state of the middleware, if any,
+// and forwards the call to the wrapped backoff, if it is resettable.
+func (m *middleware) Reset() {
+	if m.reset != nil {
+		m.reset()
+	}
+	if r, ok := m.next.(Resettable); ok {
+		r.Reset()
+	}
+}
+
+// bounds implements bounder.
+func (m *middleware) bounds(inner bounds) bounds {
+	if m.bound == nil {
+		return inner
+	}
+	return m.bound(inner)
+}
+
+// String returns the name and arguments of the middleware.
+func (m *middleware) String() string {
+	return m.name + "(" + m.args + ")"
+}
+
 // Stop value signals the backoff to stop retrying.
 const Stop = time.Duration(-1)
 
@@ -44,16 +289,33 @@ func WithJitter(j time.Duration, addOnly bool, next Backoff) Backoff {
 	if j < 0 {
 		panic("jitter must be >= 0")
 	}
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		delay, err := next.Next(err)
+	return withJitter("WithJitter", fmt.Sprintf("%v, %v", j, addOnly), j, addOnly, nil, next)
+}
+
+// WithJitterSource is like WithJitter without addOnly, i.e. it applies a jitter
+// up to ±j, but draws the jitter from the given source instead of the global
+// source of math/rand. A seeded source makes the jitter reproducible, e.g. in
+// tests. The source is guarded by a mutex, so the backoff is safe for
+// concurrent use even though r is not. Panics if j is less than 0 or r is nil.
+func WithJitterSource(j time.Duration, r *rand.Rand, next Backoff) Backoff {
+	if j < 0 {
+		panic("jitter must be >= 0")
+	}
+	return withJitter("WithJitterSource", fmt.Sprintf("%v", j), j, false, newRandSource(r), next)
+}
+
+// withJitter implements WithJitter and WithJitterSource.
+func withJitter(name, args string, j time.Duration, addOnly bool, src *randSource, next Backoff) Backoff {
+	m := wrap(name, args, next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
 		if IsStopped(delay) {
 			return Stop, err
 		}
 
 		if addOnly {
-			delay += time.Duration(rand.Int63n(int64(j)))
+			delay += time.Duration(src.int63n(int64(j)))
 		} else {
-			diff := time.Duration(rand.Int63n(int64(j)*2) - int64(j))
+			diff := time.Duration(src.int63n(int64(j)*2) - int64(j))
 			delay = delay + diff
 			if delay < 0 {
 				delay = 0
@@ -61,6 +323,53 @@ func WithJitter(j time.Duration, addOnly bool, next Backoff) Backoff {
 		}
 		return delay, err
 	})
+	m.bound = func(b bounds) bounds {
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			return addSat(d, j)
+		}).addPerRetry(j)
+	}
+	return m
+}
+
+// WithJitterCap is like WithJitter without addOnly, i.e. it adds a random
+// jitter within [-j, +j] to the delay, but clamps the jitter contribution to at
+// most maxJitter in magnitude. In contrast to capping the resulting delay (see
+// WithCappedDuration), this bounds how much randomness is added to large
+// delays, while keeping the delay itself. Panics if j or maxJitter is less than
+// 0.
+func WithJitterCap(maxJitter, j time.Duration, next Backoff) Backoff {
+	if j < 0 || maxJitter < 0 {
+		panic("jitter must be >= 0")
+	}
+	m := wrap("WithJitterCap", fmt.Sprintf("%v, %v", maxJitter, j), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		diff := time.Duration(randInt63n(int64(j)*2) - int64(j))
+		if diff > maxJitter {
+			diff = maxJitter
+		} else if diff < -maxJitter {
+			diff = -maxJitter
+		}
+		if diff > 0 {
+			delay = addSat(delay, diff)
+		} else if delay += diff; delay < 0 {
+			delay = 0
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		bound := j
+		if maxJitter < bound {
+			bound = maxJitter
+		}
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			return addSat(d, bound)
+		}).addPerRetry(bound)
+	}
+	return m
 }
 
 // WithJitterPercent wraps a backoff function and adds the specified jitter
@@ -73,18 +382,34 @@ func WithJitterPercent(j uint64, addOnly bool, next Backoff) Backoff {
 	if j < 0 && j > 100 {
 		panic("jitter must be between 0 and 100")
 	}
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		delay, err := next.Next(err)
+	return withJitterPercent("WithJitterPercent", fmt.Sprintf("%v, %v", j, addOnly), j, addOnly, nil, next)
+}
+
+// WithJitterPercentSource is like WithJitterPercent without addOnly, i.e. it
+// applies a jitter up to ±j%, but draws the jitter from the given source
+// instead of the global source of math/rand (see WithJitterSource). Panics if j
+// is greater than 100 or r is nil.
+func WithJitterPercentSource(j uint64, r *rand.Rand, next Backoff) Backoff {
+	if j > 100 {
+		panic("jitter must be between 0 and 100")
+	}
+	return withJitterPercent("WithJitterPercentSource", fmt.Sprintf("%v", j), j, false, newRandSource(r), next)
+}
+
+// withJitterPercent implements WithJitterPercent and WithJitterPercentSource.
+func withJitterPercent(name, args string, j uint64, addOnly bool, src *randSource, next Backoff) Backoff {
+	m := wrap(name, args, next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
 		if IsStopped(delay) {
 			return Stop, err
 		}
 
 		var top int64
 		if addOnly {
-			top = rand.Int63n(int64(j))
+			top = src.int63n(int64(j))
 		} else {
 			// get random value between -j and +j
-			top = rand.Int63n(int64(j)*2) - int64(j)
+			top = src.int63n(int64(j)*2) - int64(j)
 		}
 		pct := 1 + float64(top)/100.0
 
@@ -94,101 +419,1394 @@ func WithJitterPercent(j uint64, addOnly bool, next Backoff) Backoff {
 		}
 		return delay, err
 	})
+	m.bound = func(b bounds) bounds {
+		f := 1 + float64(j)/100
+		b.total = scaleSat(b.total, f)
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			return scaleSat(d, f)
+		})
+	}
+	return m
+}
+
+// WithDecayingJitter wraps a backoff function and applies a jitter, whose
+// magnitude shrinks with every attempt. The jitter fraction of the n-th delay
+// (0-based) is initialFraction * decay^n, and the delay is varied by up to
+// ±fraction. For example, with an initialFraction of 0.5 and a decay of 0.5,
+// the first delay varies by ±50%, the second by ±25%, and so on. This spreads
+// the early retries of many clients, while the later ones converge towards
+// the computed delays. Panics if initialFraction or decay is not within [0, 1].
+func WithDecayingJitter(initialFraction, decay float64, next Backoff) Backoff {
+	if initialFraction < 0 || initialFraction > 1 {
+		panic("initialFraction must be between 0 and 1")
+	}
+	if decay < 0 || decay > 1 {
+		panic("decay must be between 0 and 1")
+	}
+
+	var attempt uint64
+	m := wrap("WithDecayingJitter", fmt.Sprintf("%v, %v", initialFraction, decay), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		n := atomic.LoadUint64(&attempt)
+		if !isPeek(ctx) {
+			n = atomic.AddUint64(&attempt, 1) - 1
+		}
+		fraction := initialFraction * math.Pow(decay, float64(n))
+		return durationSat(float64(delay) * (1 + fraction*(randFloat64()*2-1))), err
+	})
+	m.bound = func(b bounds) bounds {
+		b.total = scaleSat(b.total, 1+initialFraction)
+		if b.delay != nil {
+			inner := b.delay
+			b.delay = func(n uint64) time.Duration {
+				return scaleSat(inner(n), 1+initialFraction*math.Pow(decay, float64(n-1)))
+			}
+		}
+		return b
+	}
+	m.reset = func() {
+		atomic.StoreUint64(&attempt, 0)
+	}
+	return m
+}
+
+// WithAdditiveRandom wraps a backoff function and adds a uniformly distributed
+// random value in [0, max] on top of the delay. In contrast to WithJitter, the
+// delay is never decreased, so the delay of the next backoff acts as a
+// guaranteed minimum, e.g. "1s plus up to 500ms". Panics if max is less than 0.
+func WithAdditiveRandom(max time.Duration, next Backoff) Backoff {
+	if max < 0 {
+		panic("max must be >= 0")
+	}
+	m := wrap("WithAdditiveRandom", fmt.Sprint(max), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if max > 0 && max < math.MaxInt64 {
+			delay = addSat(delay, time.Duration(randInt63n(int64(max)+1)))
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			return addSat(d, max)
+		}).addPerRetry(max)
+	}
+	return m
+}
+
+// WithGroupSpread wraps a backoff function and adds a fixed offset of
+// index/groupSize * spread to the delay, e.g. to de-synchronize a scheduled
+// batch of known size whose members all wake at the same time (like the pods
+// of a StatefulSet). In contrast to random jitter, each member of the group is
+// assigned a distinct offset, so that they are spread evenly across the window.
+// The index identifies the member within [0, groupSize).
+//
+// Panics if groupSize is less than 1, index is out of range or spread is less
+// than 0.
+func WithGroupSpread(groupSize, index int, spread time.Duration, next Backoff) Backoff {
+	if groupSize < 1 {
+		panic("groupSize must be >= 1")
+	}
+	if index < 0 || index >= groupSize {
+		panic("index must be within [0, groupSize)")
+	}
+	if spread < 0 {
+		panic("spread must be >= 0")
+	}
+	offset := durationSat(float64(spread) * float64(index) / float64(groupSize))
+
+	m := wrap("WithGroupSpread", fmt.Sprintf("%d, %d, %v", groupSize, index, spread), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+		return addSat(delay, offset), err
+	})
+	m.bound = func(b bounds) bounds {
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			return addSat(d, offset)
+		}).addPerRetry(offset)
+	}
+	return m
 }
 
-// WithMaxRetries executes the backoff function up until the maximum attempts.
+// WithMaxRetries executes the backoff function up until the maximum retries.
+// Note that it counts retries, not attempts: with a max of 3, the operation is
+// executed up to 4 times. Use WithMaxAttempts to limit the total number of
+// attempts instead. It is safe for concurrent use; the count of retries is
+// shared by all users of the backoff.
 func WithMaxRetries(max uint64, next Backoff) Backoff {
+	return maxRetries("WithMaxRetries", max, next)
+}
+
+// WithMaxAttempts is like WithMaxRetries, but limits the total number of
+// attempts, including the first one, instead of the number of retries. The
+// backoff stops on the max-th failed attempt, so WithMaxAttempts(n, b) is the
+// same as WithMaxRetries(n-1, b). For example, WithMaxAttempts(1, b) executes
+// the operation exactly once and never retries. A max of 0 behaves like 1,
+// since the first attempt is always made.
+func WithMaxAttempts(max uint64, next Backoff) Backoff {
+	var retries uint64
+	if max > 0 {
+		retries = max - 1
+	}
+	m := maxRetries("WithMaxAttempts", retries, next)
+	m.args = fmt.Sprint(max)
+	return m
+}
+
+// WithMaxConsecutiveRetries is like WithMaxRetries, but resets the count of
+// retries on each successful attempt, i.e. it tolerates up to max consecutive
+// failures, forever. This suits long-running pollers, where an occasional
+// failure must not eventually exhaust the budget. The successes are observed
+// from the retry loop (see Observer), so the backoff must be used across the
+// calls of the poller.
+func WithMaxConsecutiveRetries(max uint64, next Backoff) Backoff {
+	return &consecutiveRetries{
+		middleware: maxRetries("WithMaxConsecutiveRetries", max, next),
+	}
+}
+
+// maxRetries implements WithMaxRetries.
+func maxRetries(name string, max uint64, next Backoff) *middleware {
 	var l sync.Mutex
 	var attempt uint64
 
-	return BackoffFunc(func(err error) (time.Duration, error) {
+	m := wrap(name, fmt.Sprint(max), next, func(ctx context.Context, err error) (time.Duration, error) {
 		l.Lock()
 		defer l.Unlock()
 
 		if attempt >= max {
+			recordStop(ctx, StopMaxRetries)
 			return Stop, err
 		}
-		attempt++
+		if !isPeek(ctx) {
+			attempt++
+		}
 
-		return next.Next(err)
+		return nextContext(ctx, next, err)
 	})
+	m.bound = func(b bounds) bounds {
+		if !b.retriesOK || max < b.retries {
+			b.retries = max
+			b.retriesOK = true
+		}
+		return b
+	}
+	m.reset = func() {
+		l.Lock()
+		attempt = 0
+		l.Unlock()
+	}
+	return m
 }
 
-// WithCappedDuration sets a maximum on the duration returned from the next
-// backoff. This is NOT a total backoff time, but rather a cap on the maximum
-// value a backoff can return. Without another middleware, the backoff will
-// continue infinitely.
-func WithCappedDuration(cap time.Duration, next Backoff) Backoff {
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		delay, err := next.Next(err)
-		if IsStopped(delay) {
-			return Stop, err
-		}
+type consecutiveRetries struct {
+	*middleware
+}
 
-		if delay <= 0 || delay > cap {
-			delay = cap
+// ObserveSuccess implements Observer. It resets the count of retries, but not
+// the next backoff.
+func (b *consecutiveRetries) ObserveSuccess() {
+	b.middleware.reset()
+}
+
+// ObserveFailure implements Observer.
+func (b *consecutiveRetries) ObserveFailure(_ error) {}
+
+// WithSelectiveMaxRetries is like WithMaxRetries, but only counts the retries
+// of errors for which counts reports true. Other errors, such as expected
+// throttling, are retried without consuming the budget. The delays of all
+// errors are determined by the next backoff.
+func WithSelectiveMaxRetries(max uint64, counts func(err error) bool, next Backoff) Backoff {
+	var l sync.Mutex
+	var attempt uint64
+
+	m := wrap("WithSelectiveMaxRetries", fmt.Sprint(max), next, func(ctx context.Context, err error) (time.Duration, error) {
+		if counts(err) {
+			l.Lock()
+			if attempt >= max {
+				l.Unlock()
+				recordStop(ctx, StopMaxRetries)
+				return Stop, err
+			}
+			if !isPeek(ctx) {
+				attempt++
+			}
+			l.Unlock()
 		}
-		return delay, err
+
+		return nextContext(ctx, next, err)
 	})
+	m.reset = func() {
+		l.Lock()
+		attempt = 0
+		l.Unlock()
+	}
+	return m
 }
 
-// WithMaxDuration sets a maximum on the total amount of time a backoff should
-// execute. It's best-effort, and should not be used to guarantee an exact
-// amount of time.
-func WithMaxDuration(timeout time.Duration, next Backoff) Backoff {
-	start := time.Now()
+type maxRetriesKey struct{}
 
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		diff := timeout - time.Since(start)
-		if diff <= 0 {
+// ContextWithMaxRetries returns a copy of ctx carrying a maximum number of
+// retries. It lets the caller of an operation request a tighter retry budget,
+// e.g. to fail fast. It is honored by WithMaxRetriesContextOverride.
+func ContextWithMaxRetries(ctx context.Context, max uint64) context.Context {
+	return context.WithValue(ctx, maxRetriesKey{}, max)
+}
+
+// MaxRetriesFromContext returns the maximum number of retries carried by the
+// context, if any.
+func MaxRetriesFromContext(ctx context.Context) (uint64, bool) {
+	max, ok := ctx.Value(maxRetriesKey{}).(uint64)
+	return max, ok
+}
+
+// WithMaxRetriesContextOverride is like WithMaxRetries, but additionally
+// honors a maximum carried by the context (see ContextWithMaxRetries). The
+// smaller of both is used, i.e. the context may only tighten the configured
+// maximum, never loosen it. If the context does not carry a maximum, the
+// configured one is used.
+//
+// The context is evaluated on every call, so the maximum applies to the
+// retries of the current operation as counted by the backoff.
+func WithMaxRetriesContextOverride(max uint64, next Backoff) Backoff {
+	var l sync.Mutex
+	var attempt uint64
+
+	m := wrap("WithMaxRetriesContextOverride", fmt.Sprint(max), next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		defer l.Unlock()
+
+		limit := max
+		if cmax, ok := MaxRetriesFromContext(ctx); ok && cmax < limit {
+			limit = cmax
+		}
+		if attempt >= limit {
+			recordStop(ctx, StopMaxRetries)
 			return Stop, err
 		}
+		if !isPeek(ctx) {
+			attempt++
+		}
+
+		return nextContext(ctx, next, err)
+	})
+	m.bound = func(b bounds) bounds {
+		if !b.retriesOK || max < b.retries {
+			b.retries = max
+			b.retriesOK = true
+		}
+		return b
+	}
+	m.reset = func() {
+		l.Lock()
+		attempt = 0
+		l.Unlock()
+	}
+	return m
+}
+
+// WithAttemptOverrides replaces the delay computed by the next backoff with a
+// fixed value for specific attempts. The keys of overrides are 1-based retry
+// numbers, e.g. an entry for 3 applies to the delay before the 3rd retry. All
+// other attempts use the delay of the next backoff. A stop signaled by the next
+// backoff is never overridden.
+func WithAttemptOverrides(overrides map[uint64]time.Duration, next Backoff) Backoff {
+	// copy the overrides, so that later modifications by the caller have no
+	// effect
+	o := make(map[uint64]time.Duration, len(overrides))
+	for k, v := range overrides {
+		o[k] = v
+	}
+
+	var l sync.Mutex
+	var attempt uint64
+
+	m := wrap("WithAttemptOverrides", fmt.Sprint(o), next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		current := attempt + 1
+		if !isPeek(ctx) {
+			attempt = current
+		}
+		l.Unlock()
 
-		delay, err := next.Next(err)
+		delay, err := nextContext(ctx, next, err)
 		if IsStopped(delay) {
 			return Stop, err
 		}
 
-		if delay <= 0 || delay > diff {
-			delay = diff
+		if d, ok := o[current]; ok {
+			delay = d
 		}
 		return delay, err
 	})
+	m.bound = func(b bounds) bounds {
+		if b.delay != nil {
+			inner := b.delay
+			b.delay = func(n uint64) time.Duration {
+				if d, ok := o[n]; ok {
+					return d
+				}
+				return inner(n)
+			}
+		}
+		for _, d := range o {
+			b.total = addSat(b.total, d)
+		}
+		return b
+	}
+	m.reset = func() {
+		l.Lock()
+		attempt = 0
+		l.Unlock()
+	}
+	return m
 }
 
-type retryableError struct {
-	err error
-}
+// WithResetOnErrorChange resets the next backoff whenever the type of the
+// error differs from the one of the previous call. A new kind of failure (e.g.
+// a timeout turning into a connection refused) might indicate progress and
+// deserves fresh, fast retries. The type of the root cause is compared, i.e.
+// the error is unwrapped as far as possible first. The next backoff must
+// implement Resettable, otherwise it is never reset.
+func WithResetOnErrorChange(next Backoff) Backoff {
+	var l sync.Mutex
+	var prev reflect.Type
+	var called bool
 
-// RetryableError marks an error as retryable.
-func RetryableError(err error) error {
-	if err == nil {
-		return nil
+	m := wrap("WithResetOnErrorChange", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		typ := reflect.TypeOf(rootCause(err))
+		if isPeek(ctx) {
+			changed := called && typ != prev
+			l.Unlock()
+			if changed {
+				return Stop, errPeekUnsupported
+			}
+			return nextContext(ctx, next, err)
+		}
+		if called && typ != prev {
+			if r, ok := next.(Resettable); ok {
+				r.Reset()
+			}
+		}
+		prev = typ
+		called = true
+		l.Unlock()
+
+		return nextContext(ctx, next, err)
+	})
+	m.bound = func(b bounds) bounds {
+		// resetting the next backoff may also reset its limits
+		b.retriesOK = false
+		b.totalOK = false
+		return b
 	}
-	return &retryableError{err}
+	m.reset = func() {
+		l.Lock()
+		prev = nil
+		called = false
+		l.Unlock()
+	}
+	return m
 }
 
-// Unwrap implements error wrapping.
-func (e *retryableError) Unwrap() error {
-	return e.err
-}
+// WithDeadlineResetDetector resets the next backoff whenever the deadline of
+// the context passed to NextContext extends beyond the one of the previous
+// call. This suits long-lived operations whose context is periodically renewed
+// (e.g. a keep-alive of a streaming connection): a renewal starts a fresh
+// series of retries instead of continuing one ever-growing backoff. Calls
+// without a deadline (e.g. Next) leave the tracked deadline unchanged. The next
+// backoff must implement Resettable, otherwise it is never reset.
+func WithDeadlineResetDetector(next Backoff) Backoff {
+	var l sync.Mutex
+	var prev time.Time
 
-// Error returns the error string.
-func (e *retryableError) Error() string {
-	if e.err == nil {
-		return "retryable: <nil>"
+	m := wrap("WithDeadlineResetDetector", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		deadline, ok := ctx.Deadline()
+		extended := ok && !prev.IsZero() && deadline.After(prev)
+		if isPeek(ctx) {
+			l.Unlock()
+			if extended {
+				return Stop, errPeekUnsupported
+			}
+			return nextContext(ctx, next, err)
+		}
+		if extended {
+			if r, ok := next.(Resettable); ok {
+				r.Reset()
+			}
+		}
+		if ok {
+			prev = deadline
+		}
+		l.Unlock()
+
+		return nextContext(ctx, next, err)
+	})
+	m.bound = func(b bounds) bounds {
+		// resetting the next backoff may also reset its limits
+		b.retriesOK = false
+		b.totalOK = false
+		return b
 	}
-	return "retryable: " + e.err.Error()
+	m.reset = func() {
+		l.Lock()
+		prev = time.Time{}
+		l.Unlock()
+	}
+	return m
 }
 
-// WithRetryable wraps a backoff function and adds a check for a RetryableError.
-// When a non RetryableError then no more retry is performed.
-func WithRetryable(next Backoff) Backoff {
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		var rerr *retryableError
+// WithResetAfter resets the next backoff whenever more than d has elapsed since
+// the previous call. This suits a backoff shared across many operations of a
+// long-lived client: once the failures stop for a while, the next failure
+// starts a fresh series of retries instead of continuing the previous one. The
+// next backoff must implement Resettable, otherwise it is never reset. Panics
+// if d is not greater than 0.
+func WithResetAfter(d time.Duration, next Backoff) Backoff {
+	if d <= 0 {
+		panic("d must be greater than 0")
+	}
+
+	var l sync.Mutex
+	var last time.Time
+
+	m := wrap("WithResetAfter", fmt.Sprint(d), next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		now := timeNow()
+		expired := !last.IsZero() && now.Sub(last) > d
+		if isPeek(ctx) {
+			l.Unlock()
+			if expired {
+				return Stop, errPeekUnsupported
+			}
+			return nextContext(ctx, next, err)
+		}
+		if expired {
+			if r, ok := next.(Resettable); ok {
+				r.Reset()
+			}
+		}
+		last = now
+		l.Unlock()
+
+		return nextContext(ctx, next, err)
+	})
+	m.bound = func(b bounds) bounds {
+		// resetting the next backoff may also reset its limits
+		b.retriesOK = false
+		b.totalOK = false
+		return b
+	}
+	m.reset = func() {
+		l.Lock()
+		last = time.Time{}
+		l.Unlock()
+	}
+	return m
+}
+
+// WithResetOn resets the next backoff whenever the predicate matches the error,
+// before the delay is computed. This suits errors indicating that the
+// situation changed (e.g. a new leader was elected), so that the delays start
+// over from the beginning. Other errors are passed through unchanged. The next
+// backoff must implement Resettable, otherwise it is never reset.
+func WithResetOn(pred func(err error) bool, next Backoff) Backoff {
+	m := wrap("WithResetOn", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		if pred(err) {
+			if isPeek(ctx) {
+				return Stop, errPeekUnsupported
+			}
+			if r, ok := next.(Resettable); ok {
+				r.Reset()
+			}
+		}
+		return nextContext(ctx, next, err)
+	})
+	m.bound = func(b bounds) bounds {
+		// resetting the next backoff may also reset its limits
+		b.retriesOK = false
+		b.totalOK = false
+		return b
+	}
+	return m
+}
+
+// rootCause unwraps the error as far as possible.
+func rootCause(err error) error {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err
+		}
+		err = inner
+	}
+}
+
+// WithExponentialFloor ensures the delays of a growing backoff (e.g.
+// exponential) are at least floor during the ramp-up. As soon as the next
+// backoff returns a delay of floor or more, the floor is no longer applied and
+// the delays grow normally. In contrast to a plain minimum, it only shapes the
+// rapid-fire first attempts of a backoff with a small base.
+//
+// When combined with WithCappedDuration, the floor should be applied first
+// (i.e. be wrapped by the cap), so that a cap below floor still wins.
+func WithExponentialFloor(floor time.Duration, next Backoff) Backoff {
+	var l sync.Mutex
+	var rampedUp bool
+
+	m := wrap("WithExponentialFloor", fmt.Sprint(floor), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		l.Lock()
+		defer l.Unlock()
+
+		if !rampedUp {
+			if delay >= floor {
+				rampedUp = !isPeek(ctx)
+			} else {
+				delay = floor
+			}
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		return b.mapDelay(func(d time.Duration) time.Duration {
+			if d < floor {
+				return floor
+			}
+			return d
+		}).addPerRetry(floor)
+	}
+	m.reset = func() {
+		l.Lock()
+		rampedUp = false
+		l.Unlock()
+	}
+	return m
+}
+
+// WithScale multiplies each delay of the next backoff by the factor returned
+// from the given function. The factor is evaluated on every call, which allows
+// to relax or tighten the retry pacing at runtime (e.g. during an incident). A
+// factor of 1 leaves the delays unchanged, a factor less than 0 is treated as 0.
+// The result saturates at the maximum time.Duration.
+func WithScale(factor func() float64, next Backoff) Backoff {
+	m := wrap("WithScale", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		f := factor()
+		if f == 1 {
+			return delay, err
+		}
+		if f < 0 {
+			f = 0
+		}
+
+		return scaleSat(delay, f), err
+	})
+	m.bound = func(b bounds) bounds {
+		// the factor is unknown in advance
+		return bounds{
+			retries:   b.retries,
+			retriesOK: b.retriesOK,
+		}
+	}
+	return m
+}
+
+// Window is a daily time window with overrides of the delays. See
+// WithScheduleWindow.
+type Window struct {
+	// Start and End are the time of day the window starts (inclusive) and ends
+	// (exclusive), given as the offset from midnight in local time, e.g. 9 *
+	// time.Hour for 9:00. If End is not after Start, the window wraps around
+	// midnight, e.g. from 22:00 to 6:00.
+	Start, End time.Duration
+
+	// Multiplier scales the delays within the window, if greater than 0.
+	Multiplier float64
+
+	// Cap is a maximum on the delays within the window, if greater than 0. It
+	// is applied after the multiplier.
+	Cap time.Duration
+}
+
+// contains reports whether the time of day falls into the window.
+func (w Window) contains(timeOfDay time.Duration) bool {
+	if w.Start < w.End {
+		return timeOfDay >= w.Start && timeOfDay < w.End
+	}
+	return timeOfDay >= w.Start || timeOfDay < w.End
+}
+
+// WithScheduleWindow overrides the delays of the next backoff depending on the
+// time of day, e.g. to retry gently during business hours, but aggressively at
+// night. The current time is evaluated on every call and the first window
+// containing it applies. Outside of any window, the delays are unchanged.
+//
+// Panics if the start or end of a window is not within [0, 24h], or its
+// multiplier or cap is less than 0.
+func WithScheduleWindow(windows []Window, next Backoff) Backoff {
+	for _, w := range windows {
+		if w.Start < 0 || w.Start > 24*time.Hour || w.End < 0 || w.End > 24*time.Hour {
+			panic("window must be within [0, 24h]")
+		}
+		if w.Multiplier < 0 || w.Cap < 0 {
+			panic("multiplier and cap must be >= 0")
+		}
+	}
+	windows = append([]Window(nil), windows...)
+
+	m := wrap("WithScheduleWindow", fmt.Sprint(len(windows)), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		now := timeNow()
+		timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute +
+			time.Duration(now.Second())*time.Second + time.Duration(now.Nanosecond())
+		for _, w := range windows {
+			if !w.contains(timeOfDay) {
+				continue
+			}
+			if w.Multiplier > 0 {
+				delay = scaleSat(delay, w.Multiplier)
+			}
+			if w.Cap > 0 && delay > w.Cap {
+				delay = w.Cap
+			}
+			break
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		// the window is unknown in advance
+		return bounds{
+			retries:   b.retries,
+			retriesOK: b.retriesOK,
+		}
+	}
+	return m
+}
+
+// WithDelayFunc applies fn to each delay of the next backoff. The function
+// receives the delay and the (processed) error and returns the new delay. It
+// is the most general way of transforming delays, e.g. to shift or round them.
+// A stop of the next backoff is passed through without calling fn. If fn
+// returns a negative delay, the backoff stops.
+func WithDelayFunc(fn func(d time.Duration, err error) time.Duration, next Backoff) Backoff {
+	m := wrap("WithDelayFunc", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		delay = fn(delay, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		// the function is opaque
+		return bounds{
+			retries:   b.retries,
+			retriesOK: b.retriesOK,
+		}
+	}
+	return m
+}
+
+// WithQuantize rounds each delay up to the nearest of the given bucket
+// boundaries, e.g. the buckets of a latency histogram, to keep the cardinality
+// of retry delay metrics low and dashboards readable. Delays above the largest
+// bucket pass through unchanged and a delay of 0 stays 0. The buckets do not
+// need to be sorted. Panics if any bucket is less than 0.
+func WithQuantize(buckets []time.Duration, next Backoff) Backoff {
+	sorted := make([]time.Duration, len(buckets))
+	copy(sorted, buckets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	if len(sorted) > 0 && sorted[0] < 0 {
+		panic("buckets must be >= 0")
+	}
+
+	quantize := func(d time.Duration) time.Duration {
+		if d <= 0 {
+			return d
+		}
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= d })
+		if i == len(sorted) {
+			return d
+		}
+		return sorted[i]
+	}
+
+	m := wrap("WithQuantize", fmt.Sprint(sorted), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+		return quantize(delay), err
+	})
+	m.bound = func(b bounds) bounds {
+		// a delay is increased by at most the widest gap between two buckets
+		var gap, prev time.Duration
+		for _, bucket := range sorted {
+			if bucket-prev > gap {
+				gap = bucket - prev
+			}
+			prev = bucket
+		}
+		return b.mapDelay(quantize).addPerRetry(gap)
+	}
+	return m
+}
+
+// WithCappedDuration sets a maximum on the duration returned from the next
+// backoff. This is NOT a total backoff time, but rather a cap on the maximum
+// value a backoff can return. Without another middleware, the backoff will
+// continue infinitely.
+func WithCappedDuration(cap time.Duration, next Backoff) Backoff {
+	m := wrap("WithCappedDuration", fmt.Sprint(cap), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if delay <= 0 || delay > cap {
+			delay = cap
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		if b.delay == nil {
+			b.delay = func(uint64) time.Duration { return cap }
+		} else {
+			b = b.mapDelay(func(d time.Duration) time.Duration {
+				if d <= 0 || d > cap {
+					return cap
+				}
+				return d
+			})
+		}
+		// zero delays are raised to the cap, so the total is unknown
+		b.totalOK = false
+		return b
+	}
+	return m
+}
+
+// WithBand keeps the delays of the next backoff within [min, max]. Delays
+// below min (including zero) are raised to min and delays above max are
+// lowered to max. A stop is passed through unchanged.
+//
+// To keep jittered delays within the band, WithBand must wrap the jitter, not
+// the other way round. It panics if min is less than 0 or greater than max.
+func WithBand(min, max time.Duration, next Backoff) Backoff {
+	if min < 0 || min > max {
+		panic("band must satisfy 0 <= min <= max")
+	}
+
+	clamp := func(d time.Duration) time.Duration {
+		if d < min {
+			return min
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+
+	m := wrap("WithBand", fmt.Sprintf("%v, %v", min, max), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+		return clamp(delay), err
+	})
+	m.bound = func(b bounds) bounds {
+		if b.delay == nil {
+			b.delay = func(uint64) time.Duration { return max }
+		} else {
+			b = b.mapDelay(clamp)
+		}
+		// delays are raised to min, so the total is unknown
+		b.totalOK = false
+		return b
+	}
+	return m
+}
+
+// WithSoftCap sets a soft maximum on the duration returned from the next
+// backoff. Delays above target are not clamped to the exact target like with
+// WithCappedDuration, but replaced by a random value in [target-spread,
+// target+spread]. This avoids synchronized retries of many clients that all
+// reached the cap. Panics if spread is less than 0 or greater than target.
+func WithSoftCap(target, spread time.Duration, next Backoff) Backoff {
+	if spread < 0 || spread > target {
+		panic("spread must be between 0 and target")
+	}
+	m := wrap("WithSoftCap", fmt.Sprintf("%v, %v", target, spread), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if delay > target {
+			delay = target - spread + time.Duration(randInt63n(int64(spread)*2+1))
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		if b.delay == nil {
+			b.delay = func(uint64) time.Duration { return target + spread }
+		} else {
+			b = b.mapDelay(func(d time.Duration) time.Duration {
+				if d > target {
+					return target + spread
+				}
+				return d
+			})
+		}
+		return b.addPerRetry(spread)
+	}
+	return m
+}
+
+// WithMaxDuration sets a maximum on the total amount of time a backoff should
+// execute. It's best-effort, and should not be used to guarantee an exact
+// amount of time. If the context passed to Do has a deadline, the delay is
+// clamped to it as well, and the backoff stops once the deadline has passed.
+// It is safe for concurrent use; the duration starts on creation (or on Reset)
+// and is shared by all users of the backoff.
+func WithMaxDuration(timeout time.Duration, next Backoff) Backoff {
+	var l sync.Mutex
+	start := timeNow()
+
+	m := wrap("WithMaxDuration", fmt.Sprint(timeout), next, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		diff := timeout - timeSince(start)
+		l.Unlock()
+		if diff <= 0 {
+			recordStop(ctx, StopMaxDuration)
+			return Stop, err
+		}
+
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if delay <= 0 || delay > diff {
+			delay = diff
+		}
+		return clampToDeadline(ctx, delay, err)
+	})
+	m.bound = maxDurationBounds(timeout)
+	m.reset = func() {
+		l.Lock()
+		start = timeNow()
+		l.Unlock()
+	}
+	return m
+}
+
+// WithContextDeadline clamps the delays of the next backoff to the time
+// remaining until the deadline of the context passed to NextContext, and stops
+// once the deadline has passed. This avoids waiting for a delay, that outlasts
+// the deadline anyway, only to fail with context.DeadlineExceeded. Without a
+// deadline (e.g. when calling Next), the delays are passed through unchanged.
+func WithContextDeadline(next Backoff) Backoff {
+	return wrap("WithContextDeadline", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+		return clampToDeadline(ctx, delay, err)
+	})
+}
+
+// clampToDeadline clamps the delay to the time remaining until the deadline of
+// the context, if any. It stops if the deadline has passed.
+func clampToDeadline(ctx context.Context, delay time.Duration, err error) (time.Duration, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return delay, err
+	}
+	until := time.Until(deadline)
+	if until <= 0 {
+		recordStop(ctx, StopMaxDuration)
+		return Stop, err
+	}
+	if delay > until {
+		delay = until
+	}
+	return delay, err
+}
+
+// maxDurationBounds returns the bounds of a backoff limited to the given total
+// duration.
+func maxDurationBounds(timeout time.Duration) func(b bounds) bounds {
+	return func(b bounds) bounds {
+		if b.delay == nil {
+			b.delay = func(uint64) time.Duration { return timeout }
+		} else {
+			b = b.mapDelay(func(d time.Duration) time.Duration {
+				if d <= 0 || d > timeout {
+					return timeout
+				}
+				return d
+			})
+		}
+		if !b.totalOK || timeout < b.total {
+			b.total = timeout
+			b.totalOK = true
+		}
+		return b
+	}
+}
+
+// MaxDurationTimer is a backoff limited to a maximum total duration, that is
+// enforced proactively. See NewMaxDurationTimer.
+type MaxDurationTimer struct {
+	*middleware
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// state is 0 while the timer is pending, 1 once fired and 2 once stopped.
+	state uint32
+}
+
+// NewMaxDurationTimer is like WithMaxDuration, but starts a timer right away,
+// that proactively marks the duration as exhausted once it fires. From then
+// on, the backoff stops immediately and the channel returned by Done is
+// closed. This allows to communicate the exhaustion of the budget of a shared
+// backoff to other parts of the system, e.g. to waiters that do not call Next
+// themselves. The timer is not restarted by Reset. Call Stop once the backoff
+// is no longer needed, to release the timer before it fires.
+func NewMaxDurationTimer(timeout time.Duration, next Backoff) *MaxDurationTimer {
+	done := make(chan struct{})
+	deadline := timeNow().Add(timeout)
+
+	m := wrap("MaxDurationTimer", fmt.Sprint(timeout), next, func(ctx context.Context, err error) (time.Duration, error) {
+		select {
+		case <-done:
+			recordStop(ctx, StopMaxDuration)
+			return Stop, err
+		default:
+		}
+
+		diff := deadline.Sub(timeNow())
+		if diff <= 0 {
+			recordStop(ctx, StopMaxDuration)
+			return Stop, err
+		}
+
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if delay <= 0 || delay > diff {
+			delay = diff
+		}
+		return delay, err
+	})
+	m.bound = maxDurationBounds(timeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &MaxDurationTimer{
+		middleware: m,
+		done:       done,
+		cancel:     cancel,
+	}
+	go func() {
+		if sleep(ctx, timeout, nil) == nil && atomic.CompareAndSwapUint32(&t.state, 0, 1) {
+			close(done)
+		}
+	}()
+	return t
+}
+
+// Done returns a channel that is closed once the maximum duration is
+// exhausted.
+func (t *MaxDurationTimer) Done() <-chan struct{} {
+	return t.done
+}
+
+// Stop stops the timer, releasing its resources. If the timer has not fired
+// yet, the channel returned by Done is never closed afterwards. The backoff
+// itself still stops once the maximum duration is exhausted. It reports
+// whether the timer was stopped before it fired.
+func (t *MaxDurationTimer) Stop() bool {
+	stopped := atomic.CompareAndSwapUint32(&t.state, 0, 2)
+	t.cancel()
+	return stopped
+}
+
+// WithSampledGiveUp calls onGiveUp when the next backoff stops, but only for a
+// sampled fraction of the stops given by rate, e.g. 0.01 for 1%. This protects
+// alerting or telemetry from being overwhelmed when thousands of operations
+// give up at once during an outage. The callback receives the (processed)
+// error of the last attempt. It panics if rate is not within [0, 1].
+func WithSampledGiveUp(rate float64, onGiveUp func(err error), next Backoff) Backoff {
+	if rate < 0 || rate > 1 {
+		panic("rate must be between 0 and 1")
+	}
+
+	return wrap("WithSampledGiveUp", fmt.Sprint(rate), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) && !isPeek(ctx) && randFloat64() < rate {
+			onGiveUp(err)
+		}
+		return delay, err
+	})
+}
+
+type warmupBackoff struct {
+	start  time.Time
+	window time.Duration
+	warm   Backoff
+	cold   Backoff
+}
+
+// WithWarmup uses the warm backoff during the given window after its
+// construction and the cold backoff afterwards. This allows to give
+// dependencies some slack right after the start of a process (e.g. with more
+// lenient retries), while using tighter retries later on.
+func WithWarmup(window time.Duration, warm, cold Backoff) Backoff {
+	return &warmupBackoff{
+		start:  timeNow(),
+		window: window,
+		warm:   warm,
+		cold:   cold,
+	}
+}
+
+// Next implements Backoff. It is safe for concurrent use, as long as the warm
+// and cold backoffs are.
+func (b *warmupBackoff) Next(err error) (time.Duration, error) {
+	return b.NextContext(context.Background(), err)
+}
+
+// Peek implements Peeker.
+func (b *warmupBackoff) Peek(err error) (time.Duration, error) {
+	return b.NextContext(peekContext, err)
+}
+
+// NextContext implements ContextBackoff.
+func (b *warmupBackoff) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	if timeSince(b.start) < b.window {
+		return nextContext(ctx, b.warm, err)
+	}
+	return nextContext(ctx, b.cold, err)
+}
+
+// Reset implements Resettable. It forwards the call to the warm and cold
+// backoffs, if they are resettable. The warm-up window is not restarted.
+func (b *warmupBackoff) Reset() {
+	for _, bo := range []Backoff{b.warm, b.cold} {
+		if r, ok := bo.(Resettable); ok {
+			r.Reset()
+		}
+	}
+}
+
+// String returns a description of the backoff.
+func (b *warmupBackoff) String() string {
+	return fmt.Sprintf("WithWarmup(%v, %v, %v)", b.window, Describe(b.warm), Describe(b.cold))
+}
+
+type retryableError struct {
+	err error
+}
+
+// RetryableError marks an error as retryable.
+func RetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err}
+}
+
+// Unwrap implements error wrapping.
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Error returns the error string.
+func (e *retryableError) Error() string {
+	if e.err == nil {
+		return "retryable: <nil>"
+	}
+	return "retryable: " + e.err.Error()
+}
+
+type unrecoverableError struct {
+	err error
+}
+
+// Unrecoverable marks an error as unrecoverable. Returning such an error from
+// the retried function stops retrying immediately, regardless of the backoff,
+// which is not consulted. It is the opposite of RetryableError and works with
+// any backoff, not only with WithRetryable. Do returns the wrapped error.
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unrecoverableError{err}
+}
+
+// Unwrap implements error wrapping.
+func (e *unrecoverableError) Unwrap() error {
+	return e.err
+}
+
+// Error returns the error string.
+func (e *unrecoverableError) Error() string {
+	if e.err == nil {
+		return "unrecoverable: <nil>"
+	}
+	return "unrecoverable: " + e.err.Error()
+}
+
+// isUnrecoverable reports whether the error is marked as unrecoverable. If so,
+// it returns the error with the mark removed, if it is the outermost error.
+func isUnrecoverable(err error) (error, bool) {
+	if u, ok := err.(*unrecoverableError); ok {
+		return u.err, true
+	}
+	var u *unrecoverableError
+	return err, errors.As(err, &u)
+}
+
+// WithRetryable wraps a backoff function and adds a check for a RetryableError.
+// When a non RetryableError then no more retry is performed.
+func WithRetryable(next Backoff) Backoff {
+	return wrap("WithRetryable", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		var rerr *retryableError
 		if !errors.As(err, &rerr) {
+			recordStop(ctx, StopNonRetryable)
 			return Stop, err
 		}
-		return next.Next(rerr.Unwrap())
+		return nextContext(ctx, next, rerr.Unwrap())
 	})
 }
+
+// WithRetryIf only retries errors for which pred reports true. For such an
+// error, the next backoff is consulted; for any other error, the backoff stops.
+// In contrast to WithRetryable, the errors need not be marked as retryable by
+// the retried function.
+func WithRetryIf(pred func(err error) bool, next Backoff) Backoff {
+	return retryIf("WithRetryIf", pred, next)
+}
+
+// WithRetryableErrors only retries errors matching any of the given ones (see
+// errors.Is), e.g. io.ErrUnexpectedEOF. For any other error, the backoff
+// stops. To match errors by type instead, use WithRetryIf along with
+// errors.As.
+func WithRetryableErrors(next Backoff, retryOn ...error) Backoff {
+	return retryIf("WithRetryableErrors", func(err error) bool {
+		for _, target := range retryOn {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}, next)
+}
+
+// retryIf implements WithRetryIf and WithRetryableErrors.
+func retryIf(name string, pred func(err error) bool, next Backoff) *middleware {
+	return wrap(name, "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		if !pred(err) {
+			recordStop(ctx, StopNonRetryable)
+			return Stop, err
+		}
+		return nextContext(ctx, next, err)
+	})
+}
+
+// successMatcher is implemented by backoffs that classify certain errors as
+// success. See WithTreatAsSuccess.
+type successMatcher interface {
+	isSuccess(err error) bool
+}
+
+type treatAsSuccess struct {
+	*middleware
+	okErrors []error
+}
+
+// WithTreatAsSuccess treats the given errors as success, e.g. ErrAlreadyExists
+// for an operation that creates a resource if it does not exist. When the
+// retried function returns an error matching any of them (see errors.Is), Do
+// and its variants return nil instead of retrying or returning the error.
+// Since this changes the classification of the outcome rather than the pacing,
+// it takes effect in the retry loop; the backoff itself passes through the
+// calls to the next backoff.
+func WithTreatAsSuccess(next Backoff, okErrors ...error) Backoff {
+	return &treatAsSuccess{
+		middleware: wrap("WithTreatAsSuccess", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+			return nextContext(ctx, next, err)
+		}),
+		okErrors: okErrors,
+	}
+}
+
+// isSuccess implements successMatcher.
+func (b *treatAsSuccess) isSuccess(err error) bool {
+	for _, okErr := range b.okErrors {
+		if errors.Is(err, okErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSuccess reports whether any backoff in the chain of b classifies the
+// error as success.
+func isSuccess(b Backoff, err error) bool {
+	for _, b := range chain(b) {
+		if m, ok := b.(successMatcher); ok && m.isSuccess(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithStatusCode stops retrying based on a numeric status code carried by the
+// error, e.g. an HTTP status, a gRPC code or the status of a custom RPC
+// protocol. The extract function returns the code of an error and whether the
+// error carries one. If it does and retryable reports false for it, the
+// backoff stops. Otherwise, the next backoff is consulted.
+func WithStatusCode(extract func(err error) (code int, ok bool), retryable func(code int) bool, next Backoff) Backoff {
+	return wrap("WithStatusCode", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		if code, ok := extract(err); ok && !retryable(code) {
+			recordStop(ctx, StopNonRetryable)
+			return Stop, err
+		}
+		return nextContext(ctx, next, err)
+	})
+}
+
+// WithDelayFromMetadata overrides the delays of the next backoff with a hint
+// carried by the metadata of the error, generalizing the Retry-After header of
+// HTTP to any protocol, e.g. gRPC trailers, AMQP headers or custom RPC
+// metadata. The get function returns the metadata of an error and whether the
+// error carries any. If it does and contains the given key, the value is
+// parsed using parse and used as the delay instead. Missing, unparsable or
+// negative hints are ignored. The next backoff is consulted in any case, so
+// that its limits still apply.
+func WithDelayFromMetadata(get func(err error) (map[string]string, bool), key string, parse func(string) (time.Duration, error), next Backoff) Backoff {
+	m := wrap("WithDelayFromMetadata", key, next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		md, ok := get(err)
+		if !ok {
+			return delay, err
+		}
+		v, ok := md[key]
+		if !ok {
+			return delay, err
+		}
+		if hint, perr := parse(v); perr == nil && hint >= 0 {
+			delay = hint
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		// the hints are unknown in advance
+		return bounds{
+			retries:   b.retries,
+			retriesOK: b.retriesOK,
+		}
+	}
+	return m
+}
+
+// retryAfterer is implemented by errors carrying a delay requested by the
+// server. See WithRetryAfter.
+type retryAfterer interface {
+	RetryAfter() (time.Duration, bool)
+}
+
+// WithRetryAfter overrides the delays of the next backoff with the delay
+// requested by the server, e.g. by the Retry-After header of HTTP (see
+// ParseRetryAfter) or the retry info of gRPC. The delay is taken from the first
+// error in the chain implementing
+//
+//	interface{ RetryAfter() (time.Duration, bool) }
+//
+// if it reports true and the delay is not negative. Otherwise, the delay of the
+// next backoff is kept. The next backoff is consulted in any case, so that its
+// limits still apply.
+func WithRetryAfter(next Backoff) Backoff {
+	m := wrap("WithRetryAfter", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		var ra retryAfterer
+		if errors.As(err, &ra) {
+			if hint, ok := ra.RetryAfter(); ok && hint >= 0 {
+				delay = hint
+			}
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		// the hints are unknown in advance
+		return bounds{
+			retries:   b.retries,
+			retriesOK: b.retriesOK,
+		}
+	}
+	return m
+}
+
+// WithRetryableTimeout only retries timeouts. An error is considered a timeout,
+// if it implements interface{ Timeout() bool } (such as net.Error) with
+// Timeout reporting true, or if it is rooted in context.DeadlineExceeded. For
+// a timeout, the next backoff is consulted; for any other error, the backoff
+// stops.
+func WithRetryableTimeout(next Backoff) Backoff {
+	return wrap("WithRetryableTimeout", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		if !isTimeout(err) {
+			recordStop(ctx, StopNonRetryable)
+			return Stop, err
+		}
+		return nextContext(ctx, next, err)
+	})
+}
+
+// isTimeout reports whether the error is a timeout.
+func isTimeout(err error) bool {
+	var terr interface{ Timeout() bool }
+	if errors.As(err, &terr) && terr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
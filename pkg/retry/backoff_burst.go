@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewBurst creates a backoff that retries immediately (i.e. with a delay of 0)
+// for the first immediate retries and delegates to then for the rest. Many
+// transient glitches are resolved within a few back-to-back retries, so this
+// captures the common "try a few times instantly, then back off" pattern. The
+// attempts made by the burst are not passed to then, i.e. it starts with its
+// first delay once the burst is over.
+//
+// It panics if immediate is less than 0.
+func NewBurst(immediate int, then Backoff) Backoff {
+	if immediate < 0 {
+		panic("immediate must be >= 0")
+	}
+
+	var l sync.Mutex
+	var attempt int
+
+	m := wrap("Burst", fmt.Sprint(immediate), then, func(ctx context.Context, err error) (time.Duration, error) {
+		l.Lock()
+		if attempt < immediate {
+			if !isPeek(ctx) {
+				attempt++
+			}
+			l.Unlock()
+			return 0, err
+		}
+		l.Unlock()
+
+		return nextContext(ctx, then, err)
+	})
+	m.bound = func(b bounds) bounds {
+		n := uint64(immediate)
+		if b.delay != nil {
+			inner := b.delay
+			b.delay = func(i uint64) time.Duration {
+				if i <= n {
+					return 0
+				}
+				return inner(i - n)
+			}
+		}
+		if b.retriesOK {
+			b.retries += n
+		}
+		return b
+	}
+	m.reset = func() {
+		l.Lock()
+		attempt = 0
+		l.Unlock()
+	}
+	return m
+}
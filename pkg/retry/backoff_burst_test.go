@@ -0,0 +1,84 @@
+package retry
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBurstBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		immediate int
+		then      Backoff
+		exp       []time.Duration
+	}{
+		{
+			name:      "none",
+			immediate: 0,
+			then:      NewExponential(1 * time.Second),
+			exp:       []time.Duration{1 * time.Second, 2 * time.Second},
+		},
+		{
+			name:      "burst",
+			immediate: 3,
+			then:      NewExponential(1 * time.Second),
+			exp:       []time.Duration{0, 0, 0, 1 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:      "stop",
+			immediate: 2,
+			then:      WithMaxRetries(1, NewConstant(1*time.Second)),
+			exp:       []time.Duration{0, 0, 1 * time.Second, Stop},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewBurst(tc.immediate, tc.then)
+
+			results := make([]time.Duration, len(tc.exp))
+			for i := range results {
+				results[i], _ = b.Next(nil)
+			}
+			if !reflect.DeepEqual(results, tc.exp) {
+				t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+			}
+		})
+	}
+}
+
+func TestBurstBackoffBounds(t *testing.T) {
+	t.Parallel()
+
+	b := NewBurst(2, WithMaxRetries(3, NewConstant(1*time.Second)))
+	if total, ok := MaxTotalTime(b); !ok || total != 3*time.Second {
+		t.Errorf("expected %v, %v to be %v", total, ok, 3*time.Second)
+	}
+
+	if delay, ok := Peek(b, nil); !ok || delay != 0 {
+		t.Errorf("expected %v, %v to be 0", delay, ok)
+	}
+}
+
+func ExampleNewBurst() {
+	b := NewBurst(2, NewExponential(1*time.Second))
+
+	for i := 0; i < 5; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 0s
+	// 0s
+	// 1s
+	// 2s
+	// 4s
+}
@@ -5,6 +5,10 @@ import (
 	"time"
 )
 
+type constantBackoff struct {
+	t time.Duration
+}
+
 // Constant is a wrapper around Retry that uses a constant backoff. It panics if
 // the given base is less than zero.
 func Constant(ctx context.Context, t time.Duration, f RetryFunc) error {
@@ -19,7 +23,29 @@ func NewConstant(t time.Duration) Backoff {
 		panic("t must be greater than 0")
 	}
 
-	return BackoffFunc(func(err error) (time.Duration, error) {
-		return t, err
-	})
+	return &constantBackoff{
+		t: t,
+	}
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *constantBackoff) Next(err error) (time.Duration, error) {
+	return b.t, err
+}
+
+// Peek implements Peeker.
+func (b *constantBackoff) Peek(err error) (time.Duration, error) {
+	return b.t, err
+}
+
+// bounds implements bounder.
+func (b *constantBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(uint64) time.Duration { return b.t },
+	}
+}
+
+// String returns a description of the backoff.
+func (b *constantBackoff) String() string {
+	return "Constant(" + b.t.String() + ")"
 }
@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type decorrelatedJitterBackoff struct {
+	base time.Duration
+	cap  time.Duration
+
+	l    sync.Mutex
+	prev time.Duration
+}
+
+// NewDecorrelatedJitter creates a new backoff using the "decorrelated jitter"
+// algorithm described by AWS: each delay is a random value between base and
+// three times the previous delay, capped at cap, i.e.
+//
+//	sleep = min(cap, random_between(base, prev * 3))
+//
+// Starting with prev = base. Compared to adding jitter on top of a fixed
+// schedule (see WithJitter), this de-synchronizes the retries of many
+// contending clients much better. It never stops on its own.
+//
+// It panics if base is not greater than zero or cap is less than base.
+func NewDecorrelatedJitter(base, cap time.Duration) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+	if cap < base {
+		panic("cap must not be less than base")
+	}
+
+	return &decorrelatedJitterBackoff{
+		base: base,
+		cap:  cap,
+		prev: base,
+	}
+}
+
+// delay returns a random delay following the given previous delay.
+func (b *decorrelatedJitterBackoff) delay(prev time.Duration) time.Duration {
+	upper := time.Duration(math.MaxInt64)
+	if prev <= math.MaxInt64/3 {
+		upper = prev * 3
+	}
+
+	delay := b.base
+	if upper > b.base {
+		delay += time.Duration(randInt63n(int64(upper-b.base) + 1))
+	}
+	if delay > b.cap {
+		delay = b.cap
+	}
+	return delay
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *decorrelatedJitterBackoff) Next(err error) (time.Duration, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.prev = b.delay(b.prev)
+	return b.prev, err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *decorrelatedJitterBackoff) Peek(err error) (time.Duration, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	return b.delay(b.prev), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *decorrelatedJitterBackoff) Reset() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.prev = b.base
+}
+
+// bounds implements bounder.
+func (b *decorrelatedJitterBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(uint64) time.Duration { return b.cap },
+	}
+}
+
+// String returns a description of the backoff.
+func (b *decorrelatedJitterBackoff) String() string {
+	return "DecorrelatedJitter(" + b.base.String() + ", " + b.cap.String() + ")"
+}
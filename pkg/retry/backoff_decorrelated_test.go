@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	t.Parallel()
+
+	base, cap := 10*time.Millisecond, 1*time.Second
+	b := NewDecorrelatedJitter(base, cap)
+
+	var minSeen, maxSeen time.Duration = cap, base
+	prev := base
+	for i := 0; i < 10_000; i++ {
+		delay, _ := b.Next(nil)
+		if IsStopped(delay) {
+			t.Fatal("should not stop")
+		}
+		if delay < base || delay > cap {
+			t.Fatalf("expected %v to be between %v and %v", delay, base, cap)
+		}
+		if delay > 3*prev {
+			t.Fatalf("expected %v to be at most 3 * %v", delay, prev)
+		}
+		if delay < minSeen {
+			minSeen = delay
+		}
+		if delay > maxSeen {
+			maxSeen = delay
+		}
+		prev = delay
+	}
+
+	// the values spread across the range
+	if minSeen > 2*base || maxSeen < cap {
+		t.Errorf("expected %v and %v to spread across [%v, %v]", minSeen, maxSeen, base, cap)
+	}
+}
+
+func TestDecorrelatedJitterBackoffConcurrent(t *testing.T) {
+	t.Parallel()
+
+	base, cap := 1*time.Millisecond, 100*time.Millisecond
+	b := NewDecorrelatedJitter(base, cap)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if delay, _ := b.Next(nil); delay < base || delay > cap {
+					t.Errorf("expected %v to be between %v and %v", delay, base, cap)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecorrelatedJitterBackoffReset(t *testing.T) {
+	t.Parallel()
+
+	base := 1 * time.Second
+	b := NewDecorrelatedJitter(base, 1*time.Hour)
+	for i := 0; i < 100; i++ {
+		b.Next(nil)
+	}
+
+	b.(Resettable).Reset()
+	if delay, _ := b.Next(nil); delay < base || delay > 3*base {
+		t.Errorf("expected %v to be between %v and %v", delay, base, 3*base)
+	}
+}
+
+func ExampleNewDecorrelatedJitter() {
+	ctx := context.Background()
+
+	b := NewDecorrelatedJitter(100*time.Millisecond, 30*time.Second)
+	b = WithMaxRetries(5, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
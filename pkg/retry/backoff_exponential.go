@@ -3,13 +3,18 @@ package retry
 import (
 	"context"
 	"math"
+	"strconv"
 	"sync/atomic"
 	"time"
 )
 
 type exponentialBackoff struct {
 	base    time.Duration
+	start   uint64
 	attempt uint64
+
+	// pooled is true while the backoff is handed out by AcquireExponential.
+	pooled bool
 }
 
 // Exponential is a wrapper around Retry that uses an exponential backoff. See
@@ -35,13 +40,191 @@ func NewExponential(base time.Duration) Backoff {
 	}
 }
 
+type exponentialBaseBackoff struct {
+	base    time.Duration
+	factor  float64
+	attempt uint64
+}
+
+// NewExponentialBase is like NewExponential, but multiplies the delay by the
+// given factor on each failure instead of doubling it, i.e. the delay of the
+// n-th attempt (1-based) is base * factor^(n-1), rounded to the nearest
+// nanosecond. For example, a factor of 1.5 results in 1, 1.5, 2.25, 3.375...
+// NewExponential(base) is equal to NewExponentialBase(base, 2).
+//
+// Once it overflows, the function constantly returns the maximum time.Duration
+// for a 64-bit integer.
+//
+// It panics if base is not greater than zero or factor is not greater than 1.
+func NewExponentialBase(base time.Duration, factor float64) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+	if !(factor > 1) {
+		panic("factor must be greater than 1")
+	}
+
+	return &exponentialBaseBackoff{
+		base:   base,
+		factor: factor,
+	}
+}
+
+// delay returns the delay of the n-th attempt (1-based).
+func (b *exponentialBaseBackoff) delay(n uint64) time.Duration {
+	if n <= 1 {
+		return b.base
+	}
+	return durationSat(math.Round(float64(b.base) * math.Pow(b.factor, float64(n-1))))
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *exponentialBaseBackoff) Next(err error) (time.Duration, error) {
+	return b.delay(atomic.AddUint64(&b.attempt, 1)), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *exponentialBaseBackoff) Peek(err error) (time.Duration, error) {
+	return b.delay(atomic.LoadUint64(&b.attempt) + 1), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *exponentialBaseBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, 0)
+}
+
+// bounds implements bounder.
+func (b *exponentialBaseBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: b.delay,
+	}
+}
+
+// String returns a description of the backoff.
+func (b *exponentialBaseBackoff) String() string {
+	return "ExponentialBase(" + b.base.String() + ", " + strconv.FormatFloat(b.factor, 'g', -1, 64) + ")"
+}
+
+// NewCappedExponential creates an exponential backoff, whose delays grow from
+// base up to max and plateau there (1, 2, 4, 8, max, max...). It is equal to
+// WithCappedDuration(max, NewExponential(base)). Since the exponential
+// backoff saturates instead of overflowing, the delays never drop below max,
+// however long it runs.
+//
+// It panics if base or max is not greater than zero.
+func NewCappedExponential(base, max time.Duration) Backoff {
+	if max <= 0 {
+		panic("max must be greater than 0")
+	}
+
+	return WithCappedDuration(max, NewExponential(base))
+}
+
+// NewExponentialJitter creates the commonly used composition of an exponential
+// backoff with jitter, a cap and a maximum number of retries. It is equal to:
+//
+//	WithMaxRetries(maxRetries,
+//		WithCappedDuration(cap,
+//			WithJitterPercent(jitterPct, false,
+//				NewExponential(base))))
+//
+// The jitter of up to ±jitterPct% is applied to the exponential delay before
+// the cap, so that the cap is a hard upper bound of every delay (applying it
+// after the cap may exceed the cap by the jitter, see Validate). Consequently,
+// once the exponential delay exceeds the cap by more than the jitter, all
+// delays equal the cap. The maximum number of retries is applied last, so that
+// it limits the composition as a whole.
+//
+// It panics if base or cap is not greater than zero or jitterPct is greater
+// than 100.
+func NewExponentialJitter(base, cap time.Duration, maxRetries uint64, jitterPct uint64) Backoff {
+	if cap <= 0 {
+		panic("cap must be greater than 0")
+	}
+	if jitterPct > 100 {
+		panic("jitter must be between 0 and 100")
+	}
+
+	return WithMaxRetries(maxRetries,
+		WithCappedDuration(cap,
+			WithJitterPercent(jitterPct, false,
+				NewExponential(base))))
+}
+
+// overflowShift is a shift, that overflows any base of an exponential backoff
+// to a non-positive value.
+const overflowShift = 63
+
+// NewExponentialFrom is like NewExponential, but starts at the given attempt
+// (0-based) instead of the first one, i.e. the first delay is base *
+// 2^startAttempt. This allows to resume a persisted operation without
+// repeating the rapid early retries. NewExponentialFrom(base, 0) is equal to
+// NewExponential(base). Reset restores the start attempt.
+//
+// A start attempt that would overflow results in the maximum time.Duration.
+//
+// It panics if the given base is less than zero.
+func NewExponentialFrom(base time.Duration, startAttempt uint64) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	// a start that overflows would shift the base into arbitrary values
+	if startAttempt >= overflowShift || base > math.MaxInt64>>startAttempt {
+		startAttempt = overflowShift
+	}
+	return &exponentialBackoff{
+		base:    base,
+		start:   startAttempt,
+		attempt: startAttempt,
+	}
+}
+
+// delay returns the delay for the given shift of the base. A shift that
+// overflows results in the maximum time.Duration. Note that base << shift is
+// not necessarily negative on overflow, e.g. 5 << 62, so it must be checked
+// beforehand.
+func (b *exponentialBackoff) delay(shift uint64) time.Duration {
+	if shift >= overflowShift || b.base > math.MaxInt64>>shift {
+		return math.MaxInt64
+	}
+	return b.base << shift
+}
+
 // Next implements Backoff. It is safe for concurrent use.
 func (b *exponentialBackoff) Next(err error) (time.Duration, error) {
-	next := b.base << (atomic.AddUint64(&b.attempt, 1) - 1)
-	if next <= 0 {
+	shift := atomic.AddUint64(&b.attempt, 1) - 1
+	if shift >= overflowShift {
+		// keep the attempt from growing beyond the overflow
 		atomic.AddUint64(&b.attempt, ^uint64(0))
-		next = math.MaxInt64
 	}
 
-	return next, err
+	return b.delay(shift), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *exponentialBackoff) Peek(err error) (time.Duration, error) {
+	return b.delay(atomic.LoadUint64(&b.attempt)), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *exponentialBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, b.start)
+}
+
+// bounds implements bounder.
+func (b *exponentialBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(n uint64) time.Duration {
+			return b.delay(b.start + n - 1)
+		},
+	}
+}
+
+// String returns a description of the backoff.
+func (b *exponentialBackoff) String() string {
+	if b.start > 0 {
+		return "ExponentialFrom(" + b.base.String() + ", " + strconv.FormatUint(b.start, 10) + ")"
+	}
+	return "Exponential(" + b.base.String() + ")"
 }
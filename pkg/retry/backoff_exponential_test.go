@@ -1,7 +1,10 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"sort"
@@ -102,6 +105,348 @@ func TestExponentialBackoff(t *testing.T) {
 	}
 }
 
+func TestExponentialBackoffOverflow(t *testing.T) {
+	t.Parallel()
+
+	// 5 << 62 wraps around to a positive value, which concurrent calls may hit
+	// past the first overflow
+	for _, base := range []time.Duration{1, 3, 5, 7 * time.Second} {
+		for shift := uint64(55); shift < 70; shift++ {
+			b := NewExponential(base).(*exponentialBackoff)
+			b.attempt = shift
+
+			exp := time.Duration(math.MaxInt64)
+			if float64(base)*math.Pow(2, float64(shift)) < math.MaxInt64 {
+				exp = base << shift
+			}
+			if delay, _ := b.Peek(nil); delay != exp {
+				t.Errorf("%v << %d: expected peek %v to be %v", base, shift, delay, exp)
+			}
+			if delay, _ := b.Next(nil); delay != exp {
+				t.Errorf("%v << %d: expected %v to be %v", base, shift, delay, exp)
+			}
+		}
+	}
+}
+
+func TestExponentialBackoffReset(t *testing.T) {
+	t.Parallel()
+
+	b := NewExponential(1 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		results := make([]time.Duration, 3)
+		for j := range results {
+			results[j], _ = b.Next(nil)
+		}
+
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+
+		b.(Resettable).Reset()
+	}
+}
+
+func TestExponentialBackoffFrom(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		base  time.Duration
+		start uint64
+		exp   []time.Duration
+	}{
+		{
+			name:  "zero",
+			base:  1 * time.Second,
+			start: 0,
+			exp:   []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:  "mid_curve",
+			base:  1 * time.Second,
+			start: 3,
+			exp:   []time.Duration{8 * time.Second, 16 * time.Second, 32 * time.Second},
+		},
+		{
+			name:  "overflow",
+			base:  17 * time.Nanosecond,
+			start: 60,
+			exp:   []time.Duration{math.MaxInt64, math.MaxInt64, math.MaxInt64},
+		},
+		{
+			name:  "huge",
+			base:  1 * time.Nanosecond,
+			start: math.MaxUint64,
+			exp:   []time.Duration{math.MaxInt64, math.MaxInt64, math.MaxInt64},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewExponentialFrom(tc.base, tc.start)
+
+			for i := 0; i < 2; i++ {
+				results := make([]time.Duration, len(tc.exp))
+				for j := range results {
+					results[j], _ = b.Next(nil)
+				}
+
+				if !reflect.DeepEqual(results, tc.exp) {
+					t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+				}
+
+				// Reset restores the start attempt
+				b.(Resettable).Reset()
+			}
+		})
+	}
+}
+
+func ExampleNewExponentialFrom() {
+	// e.g. restored from a persisted operation
+	var attempts uint64 = 3
+
+	b := NewExponentialFrom(1*time.Second, attempts)
+
+	for i := 0; i < 3; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 8s
+	// 16s
+	// 32s
+}
+
+func TestExponentialBaseBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		base   time.Duration
+		factor float64
+		tries  int
+		exp    []time.Duration
+	}{
+		{
+			name:   "gentle",
+			base:   1 * time.Second,
+			factor: 1.5,
+			tries:  6,
+			exp: []time.Duration{
+				1 * time.Second,
+				1500 * time.Millisecond,
+				2250 * time.Millisecond,
+				3375 * time.Millisecond,
+				5062500 * time.Microsecond,
+				7593750 * time.Microsecond,
+			},
+		},
+		{
+			name:   "rounded",
+			base:   1 * time.Nanosecond,
+			factor: 1.5,
+			tries:  5,
+			exp: []time.Duration{
+				1 * time.Nanosecond,
+				2 * time.Nanosecond,
+				2 * time.Nanosecond,
+				3 * time.Nanosecond,
+				5 * time.Nanosecond,
+			},
+		},
+		{
+			name:   "steep",
+			base:   1 * time.Second,
+			factor: 3,
+			tries:  4,
+			exp: []time.Duration{
+				1 * time.Second,
+				3 * time.Second,
+				9 * time.Second,
+				27 * time.Second,
+			},
+		},
+		{
+			name:   "overflow",
+			base:   math.MaxInt64 / 2,
+			factor: 3,
+			tries:  3,
+			exp: []time.Duration{
+				math.MaxInt64 / 2,
+				math.MaxInt64,
+				math.MaxInt64,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewExponentialBase(tc.base, tc.factor)
+
+			resultsCh := make(chan time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				go func() {
+					r, _ := b.Next(nil)
+					resultsCh <- r
+				}()
+			}
+
+			results := make([]time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				select {
+				case delay := <-resultsCh:
+					results[i] = delay
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout")
+				}
+			}
+			sort.Slice(results, func(i, j int) bool {
+				return results[i] < results[j]
+			})
+
+			if !reflect.DeepEqual(results, tc.exp) {
+				t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+			}
+		})
+	}
+}
+
+func TestExponentialBaseBackoffPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		base   time.Duration
+		factor float64
+	}{
+		{name: "base", base: 0, factor: 2},
+		{name: "factor_one", base: 1 * time.Second, factor: 1},
+		{name: "factor_less", base: 1 * time.Second, factor: 0.5},
+		{name: "factor_nan", base: 1 * time.Second, factor: math.NaN()},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewExponentialBase(tc.base, tc.factor)
+		})
+	}
+}
+
+func TestNewCappedExponential(t *testing.T) {
+	t.Parallel()
+
+	b := NewCappedExponential(1*time.Second, 10*time.Second)
+
+	exp := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	// drive the sequence well past the overflow of the exponential backoff
+	for len(exp) < 100 {
+		exp = append(exp, 10*time.Second)
+	}
+
+	for i, e := range exp {
+		if delay, _ := b.Next(nil); delay != e {
+			t.Fatalf("attempt %d: expected %v to be %v", i+1, delay, e)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewCappedExponential(1*time.Second, 0)
+}
+
+func TestNewExponentialJitter(t *testing.T) {
+	t.Parallel()
+
+	const retries = 6
+	base, cap := 1*time.Millisecond, 4*time.Millisecond
+	b := NewExponentialJitter(base, cap, retries, 10)
+
+	if err := Validate(b); err != nil {
+		t.Errorf("expected no validation err, got %v", err)
+	}
+
+	var attempts int
+	var delays []time.Duration
+	err := DoWithHooks(context.Background(), b, func(_ context.Context) error {
+		attempts++
+		return io.EOF
+	}, func(_ uint64, delay time.Duration, _ error) {
+		delays = append(delays, delay)
+	})
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected %v to be %v", err, io.EOF)
+	}
+	if attempts != retries+1 {
+		t.Errorf("expected %d attempts, got %d", retries+1, attempts)
+	}
+	if len(delays) != retries {
+		t.Fatalf("expected %d delays, got %v", retries, delays)
+	}
+
+	for i, delay := range delays {
+		min, max := base<<i*9/10, base<<i*11/10
+		if max > cap {
+			min, max = cap*9/10, cap
+		}
+		if delay < min || delay > max {
+			t.Errorf("retry %d: expected %v to be between %v and %v", i+1, delay, min, max)
+		}
+	}
+}
+
+func TestNewExponentialJitterPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		base      time.Duration
+		cap       time.Duration
+		jitterPct uint64
+	}{
+		{name: "base", base: 0, cap: 1 * time.Second, jitterPct: 10},
+		{name: "cap", base: 1 * time.Second, cap: 0, jitterPct: 10},
+		{name: "jitter", base: 1 * time.Second, cap: 1 * time.Second, jitterPct: 101},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewExponentialJitter(tc.base, tc.cap, 3, tc.jitterPct)
+		})
+	}
+}
+
 func ExampleNewExponential() {
 	b := NewExponential(1 * time.Second)
 
@@ -11,6 +11,7 @@ import (
 type state [2]time.Duration
 
 type fibonacciBackoff struct {
+	base  time.Duration
 	state unsafe.Pointer
 }
 
@@ -34,6 +35,7 @@ func NewFibonacci(base time.Duration) Backoff {
 	}
 
 	return &fibonacciBackoff{
+		base:  base,
 		state: unsafe.Pointer(&state{0, base}),
 	}
 }
@@ -54,3 +56,39 @@ func (b *fibonacciBackoff) Next(err error) (time.Duration, error) {
 		}
 	}
 }
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *fibonacciBackoff) Peek(err error) (time.Duration, error) {
+	currState := (*state)(atomic.LoadPointer(&b.state))
+	next := currState[0] + currState[1]
+	if next <= 0 {
+		return math.MaxInt64, err
+	}
+	return next, err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *fibonacciBackoff) Reset() {
+	atomic.StorePointer(&b.state, unsafe.Pointer(&state{0, b.base}))
+}
+
+// bounds implements bounder.
+func (b *fibonacciBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(n uint64) time.Duration {
+			prev, curr := time.Duration(0), b.base
+			for i := uint64(0); i < n; i++ {
+				if curr > math.MaxInt64-prev {
+					return math.MaxInt64
+				}
+				prev, curr = curr, prev+curr
+			}
+			return curr
+		},
+	}
+}
+
+// String returns a description of the backoff.
+func (b *fibonacciBackoff) String() string {
+	return "Fibonacci(" + b.base.String() + ")"
+}
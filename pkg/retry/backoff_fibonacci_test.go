@@ -114,6 +114,25 @@ func TestFibonacciBackoff(t *testing.T) {
 	}
 }
 
+func TestFibonacciBackoffReset(t *testing.T) {
+	t.Parallel()
+
+	b := NewFibonacci(1 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		results := make([]time.Duration, 3)
+		for j := range results {
+			results[j], _ = b.Next(nil)
+		}
+
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+
+		b.(Resettable).Reset()
+	}
+}
+
 func ExampleNewFibonacci() {
 	b := NewFibonacci(1 * time.Second)
 
@@ -0,0 +1,145 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+type grpcBackoff struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	attempt    uint64
+
+	// policy is true for the retry policy, which subtracts the jitter from
+	// the capped delay instead of spreading it around it.
+	policy bool
+}
+
+// NewGRPCBackoff creates a new backoff that implements the retry policy of the
+// gRPC service config (gRFC A6). This allows clients to mirror the retry policy
+// of a gRPC service. The delay before the n-th retry is:
+//
+//	min(initial * multiplier^(n-1), max) * (1 - jitter * random(0, 1))
+//
+// A jitter of 1 results in the exact formula of gRFC A6, i.e. a delay drawn
+// uniformly from [0, min(initialBackoff * backoffMultiplier^(n-1),
+// maxBackoff)). A jitter of 0 disables the randomness. The maxAttempts of the
+// policy is not part of the backoff; combine it with WithMaxAttempts, e.g.:
+//
+//	WithMaxAttempts(4, NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1))
+//
+// It panics if initial is less than or equal to zero, max is less than
+// initial, multiplier is less than or equal to zero or jitter is not between 0
+// and 1.
+func NewGRPCBackoff(initial, max time.Duration, multiplier, jitter float64) Backoff {
+	if initial <= 0 {
+		panic("initial must be greater than 0")
+	}
+	if max < initial {
+		panic("max must be greater than or equal to initial")
+	}
+	if !(multiplier > 0) {
+		panic("multiplier must be greater than 0")
+	}
+	if jitter < 0 || jitter > 1 {
+		panic("jitter must be between 0 and 1")
+	}
+
+	return &grpcBackoff{
+		initial:    initial,
+		max:        max,
+		multiplier: multiplier,
+		jitter:     jitter,
+		policy:     true,
+	}
+}
+
+// NewGRPCConnectionBackoff creates a new backoff that implements the
+// connection backoff of gRPC, i.e. the backoff a gRPC client uses to reconnect
+// to a server. The delay before the n-th retry is:
+//
+//	min(initial * multiplier^(n-1), max) * (1 + jitter * random(-1, 1))
+//
+// gRPC's defaults are an initial backoff of 1s, a multiplier of 1.6, a jitter
+// of 0.2 and a max backoff of 120s. For the retry policy of the gRPC service
+// config, use NewGRPCBackoff instead.
+//
+// It panics if initial is less than or equal to zero, max is less than
+// initial, multiplier is less than 1 or jitter is not between 0 and 1.
+func NewGRPCConnectionBackoff(initial, max time.Duration, multiplier, jitter float64) Backoff {
+	if initial <= 0 {
+		panic("initial must be greater than 0")
+	}
+	if max < initial {
+		panic("max must be greater than or equal to initial")
+	}
+	if multiplier < 1 {
+		panic("multiplier must be greater than or equal to 1")
+	}
+	if jitter < 0 || jitter > 1 {
+		panic("jitter must be between 0 and 1")
+	}
+
+	return &grpcBackoff{
+		initial:    initial,
+		max:        max,
+		multiplier: multiplier,
+		jitter:     jitter,
+	}
+}
+
+// delay returns the delay before the n-th retry without jitter.
+func (b *grpcBackoff) delay(n uint64) float64 {
+	backoff := float64(b.initial) * math.Pow(b.multiplier, float64(n-1))
+	if backoff > float64(b.max) {
+		backoff = float64(b.max)
+	}
+	return backoff
+}
+
+// randomize applies the randomness to the delay.
+func (b *grpcBackoff) randomize(backoff float64) time.Duration {
+	if b.policy {
+		return durationSat(backoff * (1 - b.jitter*randFloat64()))
+	}
+	return durationSat(backoff * (1 + b.jitter*(randFloat64()*2-1)))
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *grpcBackoff) Next(err error) (time.Duration, error) {
+	return b.randomize(b.delay(atomic.AddUint64(&b.attempt, 1))), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *grpcBackoff) Peek(err error) (time.Duration, error) {
+	return b.randomize(b.delay(atomic.LoadUint64(&b.attempt) + 1)), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *grpcBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, 0)
+}
+
+// bounds implements bounder.
+func (b *grpcBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(n uint64) time.Duration {
+			if b.policy {
+				return durationSat(b.delay(n))
+			}
+			return durationSat(b.delay(n) * (1 + b.jitter))
+		},
+	}
+}
+
+// String returns a description of the backoff.
+func (b *grpcBackoff) String() string {
+	if b.policy {
+		return fmt.Sprintf("GRPC(%v, %v, %v, %v)", b.initial, b.max, b.multiplier, b.jitter)
+	}
+	return fmt.Sprintf("GRPCConnection(%v, %v, %v, %v)", b.initial, b.max, b.multiplier, b.jitter)
+}
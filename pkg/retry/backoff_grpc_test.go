@@ -0,0 +1,199 @@
+package retry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGRPCBackoff(t *testing.T) {
+	t.Parallel()
+
+	// the example policy of gRFC A6
+	caps := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1 * time.Second,
+		1 * time.Second,
+	}
+
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 1_000; i++ {
+			b := NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1)
+			for n, max := range caps {
+				delay, _ := b.Next(nil)
+				if delay < 0 || delay >= max {
+					t.Fatalf("retry %d: expected %v to be within [0, %v)", n+1, delay, max)
+				}
+			}
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 1_000; i++ {
+			b := NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 0.2)
+			b.Next(nil)
+			delay, _ := b.Next(nil)
+			if min, max := 160*time.Millisecond, 200*time.Millisecond; delay <= min || delay > max {
+				t.Errorf("expected %v to be within (%v, %v]", delay, min, max)
+			}
+		}
+	})
+
+	t.Run("no_jitter", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 0)
+		for n, want := range caps {
+			if delay, _ := b.Next(nil); delay != want {
+				t.Errorf("retry %d: expected %v to be %v", n+1, delay, want)
+			}
+		}
+	})
+
+	t.Run("max_attempts", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxAttempts(4, NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1))
+		var retries int
+		for {
+			delay, _ := b.Next(nil)
+			if IsStopped(delay) {
+				break
+			}
+			retries++
+		}
+		if got, want := retries, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 0, 1)
+	})
+
+	t.Run("panics_jitter", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic")
+			}
+		}()
+		NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1.5)
+	})
+}
+
+func TestGRPCBackoffDeterministic(t *testing.T) {
+	SetDeterministic(true)
+	defer SetDeterministic(false)
+
+	// the center of the range [0, cap) of the example policy of gRFC A6
+	exp := []time.Duration{
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+	}
+
+	b := NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1)
+	for n, want := range exp {
+		if delay, _ := b.Next(nil); delay != want {
+			t.Errorf("retry %d: expected %v to be %v", n+1, delay, want)
+		}
+	}
+}
+
+func TestGRPCConnectionBackoff(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		// the default connection backoff of gRPC without jitter
+		exp := []time.Duration{
+			1 * time.Second,
+			1600 * time.Millisecond,
+			2560 * time.Millisecond,
+			4096 * time.Millisecond,
+			6553600 * time.Microsecond,
+			10485760 * time.Microsecond,
+			16777216 * time.Microsecond,
+			26843545600 * time.Nanosecond,
+			42949672960 * time.Nanosecond,
+			68719476736 * time.Nanosecond,
+			109951162777 * time.Nanosecond,
+			120 * time.Second,
+			120 * time.Second,
+		}
+
+		b := NewGRPCConnectionBackoff(1*time.Second, 120*time.Second, 1.6, 0)
+		for n, want := range exp {
+			delay, _ := b.Next(nil)
+
+			// allow for floating point rounding
+			if diff := delay - want; diff < -1 || diff > 1 {
+				t.Errorf("retry %d: expected %v to be %v", n+1, delay, want)
+			}
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 1_000; i++ {
+			b := NewGRPCConnectionBackoff(1*time.Second, 120*time.Second, 1.6, 0.2)
+			b.Next(nil)
+			delay, _ := b.Next(nil)
+			if min, max := 1280*time.Millisecond, 1920*time.Millisecond; delay < min || delay > max {
+				t.Errorf("expected %v to be between %v and %v", delay, min, max)
+			}
+		}
+	})
+}
+
+func ExampleNewGRPCBackoff() {
+	// mirrors the retry policy of a service config:
+	//
+	//	"retryPolicy": {
+	//	  "maxAttempts": 4,
+	//	  "initialBackoff": "0.1s",
+	//	  "maxBackoff": "1s",
+	//	  "backoffMultiplier": 2
+	//	}
+	b := WithMaxAttempts(4, NewGRPCBackoff(100*time.Millisecond, 1*time.Second, 2, 1))
+
+	fmt.Println(Describe(b))
+	// Output:
+	// WithMaxAttempts(4) -> GRPC(100ms, 1s, 2, 1)
+}
+
+func ExampleNewGRPCConnectionBackoff() {
+	b := NewGRPCConnectionBackoff(1*time.Second, 120*time.Second, 1.6, 0)
+
+	for i := 0; i < 5; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 1s
+	// 1.6s
+	// 2.56s
+	// 4.096s
+	// 6.5536s
+}
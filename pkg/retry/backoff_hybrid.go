@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+type hybridBackoff struct {
+	base       time.Duration
+	switchAt   time.Duration
+	linearStep time.Duration
+	attempt    uint64
+}
+
+// NewHybrid creates a new backoff that grows exponentially and then linearly.
+// Starting at base, the delay doubles on each failure until it reaches
+// switchAt. From then on, linearStep is added on each failure instead (e.g.
+// base 1s, switchAt 10s and linearStep 5s results in 1, 2, 4, 8, 10, 15,
+// 20...). This avoids the overly long delays of a purely exponential backoff,
+// while still backing off further.
+//
+// Once it overflows, the function constantly returns the maximum time.Duration
+// for a 64-bit integer.
+//
+// It panics if base is not greater than zero, switchAt is less than base or
+// linearStep is negative.
+func NewHybrid(base, switchAt, linearStep time.Duration) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+	if switchAt < base {
+		panic("switchAt must not be less than base")
+	}
+	if linearStep < 0 {
+		panic("linearStep must not be negative")
+	}
+
+	return &hybridBackoff{
+		base:       base,
+		switchAt:   switchAt,
+		linearStep: linearStep,
+	}
+}
+
+// delay returns the delay of the n-th attempt (1-based).
+func (b *hybridBackoff) delay(n uint64) time.Duration {
+	d := b.base
+	for i := uint64(1); i < n; i++ {
+		if d >= b.switchAt {
+			return addSat(b.switchAt, mulSat(b.linearStep, n-i))
+		}
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+		} else {
+			d *= 2
+		}
+	}
+	if d > b.switchAt {
+		d = b.switchAt
+	}
+	return d
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *hybridBackoff) Next(err error) (time.Duration, error) {
+	return b.delay(atomic.AddUint64(&b.attempt, 1)), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *hybridBackoff) Peek(err error) (time.Duration, error) {
+	return b.delay(atomic.LoadUint64(&b.attempt) + 1), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *hybridBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, 0)
+}
+
+// bounds implements bounder.
+func (b *hybridBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: b.delay,
+	}
+}
+
+// String returns a description of the backoff.
+func (b *hybridBackoff) String() string {
+	return "Hybrid(" + b.base.String() + ", " + b.switchAt.String() + ", " + b.linearStep.String() + ")"
+}
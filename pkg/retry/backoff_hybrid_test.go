@@ -0,0 +1,192 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHybridBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		base       time.Duration
+		switchAt   time.Duration
+		linearStep time.Duration
+		tries      int
+		exp        []time.Duration
+	}{
+		{
+			name:       "single",
+			base:       1 * time.Nanosecond,
+			switchAt:   1 * time.Nanosecond,
+			linearStep: 1 * time.Nanosecond,
+			tries:      1,
+			exp: []time.Duration{
+				1 * time.Nanosecond,
+			},
+		},
+		{
+			name:       "switch",
+			base:       1 * time.Second,
+			switchAt:   10 * time.Second,
+			linearStep: 5 * time.Second,
+			tries:      7,
+			exp: []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				8 * time.Second,
+				10 * time.Second,
+				15 * time.Second,
+				20 * time.Second,
+			},
+		},
+		{
+			name:       "exact",
+			base:       1 * time.Second,
+			switchAt:   4 * time.Second,
+			linearStep: 1 * time.Second,
+			tries:      5,
+			exp: []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				5 * time.Second,
+				6 * time.Second,
+			},
+		},
+		{
+			name:       "constant",
+			base:       1 * time.Second,
+			switchAt:   2 * time.Second,
+			linearStep: 0,
+			tries:      4,
+			exp: []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				2 * time.Second,
+				2 * time.Second,
+			},
+		},
+		{
+			name:       "overflow",
+			base:       1 * time.Hour,
+			switchAt:   math.MaxInt64 - 1,
+			linearStep: 1 * time.Hour,
+			tries:      25,
+			exp: func() []time.Duration {
+				exp := make([]time.Duration, 0, 25)
+				for i := 0; i < 22; i++ {
+					exp = append(exp, time.Hour<<i)
+				}
+				return append(exp, math.MaxInt64-1, math.MaxInt64, math.MaxInt64)
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewHybrid(tc.base, tc.switchAt, tc.linearStep)
+
+			resultsCh := make(chan time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				go func() {
+					r, _ := b.Next(nil)
+					resultsCh <- r
+				}()
+			}
+
+			results := make([]time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				select {
+				case delay := <-resultsCh:
+					results[i] = delay
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout")
+				}
+			}
+			sort.Slice(results, func(i, j int) bool {
+				return results[i] < results[j]
+			})
+
+			if !reflect.DeepEqual(results, tc.exp) {
+				t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+			}
+		})
+	}
+}
+
+func TestHybridBackoffReset(t *testing.T) {
+	t.Parallel()
+
+	b := NewHybrid(1*time.Second, 2*time.Second, 1*time.Second)
+
+	for i := 0; i < 2; i++ {
+		results := make([]time.Duration, 3)
+		for j := range results {
+			results[j], _ = b.Next(nil)
+		}
+
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+
+		b.(Resettable).Reset()
+	}
+}
+
+func TestHybridBackoffPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		base       time.Duration
+		switchAt   time.Duration
+		linearStep time.Duration
+	}{
+		{name: "base", base: 0, switchAt: 1 * time.Second, linearStep: 1 * time.Second},
+		{name: "switch", base: 2 * time.Second, switchAt: 1 * time.Second, linearStep: 1 * time.Second},
+		{name: "step", base: 1 * time.Second, switchAt: 1 * time.Second, linearStep: -1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewHybrid(tc.base, tc.switchAt, tc.linearStep)
+		})
+	}
+}
+
+func ExampleNewHybrid() {
+	b := NewHybrid(1*time.Second, 10*time.Second, 5*time.Second)
+
+	for i := 0; i < 7; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 1s
+	// 2s
+	// 4s
+	// 8s
+	// 10s
+	// 15s
+	// 20s
+}
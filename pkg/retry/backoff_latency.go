@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+type latencyAdaptiveBackoff struct {
+	minBase time.Duration
+	maxBase time.Duration
+	alpha   float64
+
+	l       sync.Mutex
+	ewma    float64
+	sampled bool
+	attempt uint64
+}
+
+// NewLatencyAdaptive creates a new exponential backoff, whose base tracks the
+// latency of the attempts, so that the backoff scales with how slow the
+// dependency currently is. The base is the exponentially weighted moving
+// average (EWMA) of the observed latencies, bounded by minBase and maxBase.
+// The smoothing factor alpha within (0, 1] is the weight of the most recent
+// latency, e.g. 0.2 for a slowly adapting average. Until the first latency is
+// observed, the base is minBase.
+//
+// The latencies are observed from the retry loop (see LatencyObserver), so
+// the backoff may also be shared across calls, in order to keep the average.
+// Reset restarts the exponential growth, but keeps the average.
+//
+// Once it overflows, the function constantly returns the maximum time.Duration
+// for a 64-bit integer.
+//
+// It panics if minBase is not greater than 0, maxBase is less than minBase or
+// alpha is not within (0, 1].
+func NewLatencyAdaptive(minBase, maxBase time.Duration, alpha float64) Backoff {
+	if minBase <= 0 {
+		panic("minBase must be greater than 0")
+	}
+	if maxBase < minBase {
+		panic("maxBase must not be less than minBase")
+	}
+	if alpha <= 0 || alpha > 1 {
+		panic("alpha must be within (0, 1]")
+	}
+
+	return &latencyAdaptiveBackoff{
+		minBase: minBase,
+		maxBase: maxBase,
+		alpha:   alpha,
+	}
+}
+
+// base returns the current base. The lock must be held.
+func (b *latencyAdaptiveBackoff) base() time.Duration {
+	if !b.sampled {
+		return b.minBase
+	}
+	base := durationSat(b.ewma)
+	if base < b.minBase {
+		return b.minBase
+	}
+	if base > b.maxBase {
+		return b.maxBase
+	}
+	return base
+}
+
+// delay returns the delay for the given base and attempt (0-based).
+func (b *latencyAdaptiveBackoff) delay(base time.Duration, attempt uint64) time.Duration {
+	if attempt >= overflowShift || base > math.MaxInt64>>attempt {
+		return math.MaxInt64
+	}
+	return base << attempt
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *latencyAdaptiveBackoff) Next(err error) (time.Duration, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	delay := b.delay(b.base(), b.attempt)
+	if b.attempt < overflowShift {
+		b.attempt++
+	}
+	return delay, err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *latencyAdaptiveBackoff) Peek(err error) (time.Duration, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	return b.delay(b.base(), b.attempt), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *latencyAdaptiveBackoff) Reset() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.attempt = 0
+}
+
+// ObserveLatency implements LatencyObserver. It updates the average.
+func (b *latencyAdaptiveBackoff) ObserveLatency(d time.Duration) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if !b.sampled {
+		b.ewma = float64(d)
+		b.sampled = true
+		return
+	}
+	b.ewma = b.alpha*float64(d) + (1-b.alpha)*b.ewma
+}
+
+// ObserveSuccess implements Observer.
+func (b *latencyAdaptiveBackoff) ObserveSuccess() {}
+
+// ObserveFailure implements Observer.
+func (b *latencyAdaptiveBackoff) ObserveFailure(_ error) {}
+
+// bounds implements bounder.
+func (b *latencyAdaptiveBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: func(n uint64) time.Duration {
+			return b.delay(b.maxBase, n-1)
+		},
+	}
+}
+
+// String returns a description of the backoff.
+func (b *latencyAdaptiveBackoff) String() string {
+	return fmt.Sprintf("LatencyAdaptive(%v, %v, %v)", b.minBase, b.maxBase, b.alpha)
+}
@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyAdaptiveBackoff(t *testing.T) {
+	t.Parallel()
+
+	t.Run("min_base", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewLatencyAdaptive(1*time.Second, 10*time.Second, 0.5)
+
+		results := make([]time.Duration, 3)
+		for i := range results {
+			results[i], _ = b.Next(nil)
+		}
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+	})
+
+	t.Run("ewma", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewLatencyAdaptive(1*time.Second, 10*time.Second, 0.5)
+		lo := b.(LatencyObserver)
+
+		cases := []struct {
+			latency time.Duration
+			exp     time.Duration
+		}{
+			{latency: 2 * time.Second, exp: 2 * time.Second}, // first sample
+			{latency: 4 * time.Second, exp: 3 * time.Second}, // 0.5*4 + 0.5*2
+			{latency: 100 * time.Millisecond, exp: 1550 * time.Millisecond},
+			{latency: 0, exp: 1 * time.Second},              // below minBase
+			{latency: 1 * time.Hour, exp: 10 * time.Second}, // above maxBase
+		}
+		for i, tc := range cases {
+			lo.ObserveLatency(tc.latency)
+			if delay, _ := Peek(b, nil); delay != tc.exp {
+				t.Errorf("sample %d: expected %v to be %v", i+1, delay, tc.exp)
+			}
+		}
+
+		// the base grows exponentially
+		b.(Resettable).Reset()
+		b.Next(nil)
+		if delay, _ := b.Next(nil); delay != 20*time.Second {
+			t.Errorf("expected %v to be %v", delay, 20*time.Second)
+		}
+	})
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewLatencyAdaptive(1*time.Nanosecond, 10*time.Second, 1)
+		err := Do(context.Background(), WithMaxRetries(1, b), func(_ context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return errors.New("slow")
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+		if delay, _ := Peek(b, nil); delay < 20*time.Millisecond {
+			t.Errorf("expected %v to be at least %v", delay, 20*time.Millisecond)
+		}
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxRetries(3, NewLatencyAdaptive(1*time.Second, 2*time.Second, 0.5))
+		if total, ok := MaxTotalTime(b); !ok || total != 14*time.Second {
+			t.Errorf("expected %v, %v to be %v", total, ok, 14*time.Second)
+		}
+		if got, want := Describe(b), "WithMaxRetries(3) -> LatencyAdaptive(1s, 2s, 0.5)"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type linearBackoff struct {
+	base    time.Duration
+	attempt uint64
+}
+
+// Linear is a wrapper around Retry that uses a linear backoff. See NewLinear.
+func Linear(ctx context.Context, base time.Duration, f RetryFunc) error {
+	return Do(ctx, NewLinear(base), f)
+}
+
+// NewLinear creates a new linear backoff using the starting value of base and
+// adding base on each failure (1, 2, 3, 4, 5, 6, 7...). It results in evenly
+// spaced and predictable retries.
+//
+// Once it overflows, the function constantly returns the maximum time.Duration
+// for a 64-bit integer.
+//
+// It panics if the given base is not greater than zero.
+func NewLinear(base time.Duration) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	return &linearBackoff{
+		base: base,
+	}
+}
+
+// delay returns the delay of the n-th attempt (1-based).
+func (b *linearBackoff) delay(n uint64) time.Duration {
+	return mulSat(b.base, n)
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *linearBackoff) Next(err error) (time.Duration, error) {
+	return b.delay(atomic.AddUint64(&b.attempt, 1)), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *linearBackoff) Peek(err error) (time.Duration, error) {
+	return b.delay(atomic.LoadUint64(&b.attempt) + 1), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *linearBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, 0)
+}
+
+// bounds implements bounder.
+func (b *linearBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: b.delay,
+	}
+}
+
+// String returns a description of the backoff.
+func (b *linearBackoff) String() string {
+	return "Linear(" + b.base.String() + ")"
+}
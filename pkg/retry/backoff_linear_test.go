@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLinearBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		base  time.Duration
+		tries int
+		exp   []time.Duration
+	}{
+		{
+			name:  "single",
+			base:  1 * time.Nanosecond,
+			tries: 1,
+			exp: []time.Duration{
+				1 * time.Nanosecond,
+			},
+		},
+		{
+			name:  "max",
+			base:  10 * time.Millisecond,
+			tries: 5,
+			exp: []time.Duration{
+				10 * time.Millisecond,
+				20 * time.Millisecond,
+				30 * time.Millisecond,
+				40 * time.Millisecond,
+				50 * time.Millisecond,
+			},
+		},
+		{
+			name:  "overflow",
+			base:  math.MaxInt64 / 3,
+			tries: 5,
+			exp: []time.Duration{
+				math.MaxInt64 / 3,
+				math.MaxInt64 / 3 * 2,
+				math.MaxInt64 / 3 * 3,
+				math.MaxInt64,
+				math.MaxInt64,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewLinear(tc.base)
+
+			resultsCh := make(chan time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				go func() {
+					r, _ := b.Next(nil)
+					resultsCh <- r
+				}()
+			}
+
+			results := make([]time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				select {
+				case delay := <-resultsCh:
+					results[i] = delay
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout")
+				}
+			}
+			sort.Slice(results, func(i, j int) bool {
+				return results[i] < results[j]
+			})
+
+			if !reflect.DeepEqual(results, tc.exp) {
+				t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+			}
+		})
+	}
+}
+
+func TestLinearBackoffReset(t *testing.T) {
+	t.Parallel()
+
+	b := NewLinear(1 * time.Second)
+
+	for i := 0; i < 2; i++ {
+		results := make([]time.Duration, 3)
+		for j := range results {
+			results[j], _ = b.Next(nil)
+		}
+
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+
+		b.(Resettable).Reset()
+	}
+}
+
+func ExampleNewLinear() {
+	b := NewLinear(1 * time.Second)
+
+	for i := 0; i < 5; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 1s
+	// 2s
+	// 3s
+	// 4s
+	// 5s
+}
@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type polynomialBackoff struct {
+	base     time.Duration
+	exponent float64
+	attempt  uint64
+}
+
+// NewPolynomial creates a new polynomial backoff, whose delay of the n-th
+// attempt (1-based) is base * n^exponent. For example, an exponent of 2 results
+// in 1, 4, 9, 16, 25, 36, 49... It grows faster than a linear backoff (an
+// exponent of 1), but slower than an exponential one. The first delay is always
+// base.
+//
+// Once it overflows, the function constantly returns the maximum time.Duration
+// for a 64-bit integer.
+//
+// It panics if base is not greater than zero or exponent is negative.
+func NewPolynomial(base time.Duration, exponent float64) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+	if !(exponent >= 0) {
+		panic("exponent must not be negative")
+	}
+
+	return &polynomialBackoff{
+		base:     base,
+		exponent: exponent,
+	}
+}
+
+// delay returns the delay of the n-th attempt (1-based).
+func (b *polynomialBackoff) delay(n uint64) time.Duration {
+	if n <= 1 || b.exponent == 0 {
+		return b.base
+	}
+	return durationSat(float64(b.base) * math.Pow(float64(n), b.exponent))
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *polynomialBackoff) Next(err error) (time.Duration, error) {
+	return b.delay(atomic.AddUint64(&b.attempt, 1)), err
+}
+
+// Peek implements Peeker. It is safe for concurrent use.
+func (b *polynomialBackoff) Peek(err error) (time.Duration, error) {
+	return b.delay(atomic.LoadUint64(&b.attempt) + 1), err
+}
+
+// Reset implements Resettable. It is safe for concurrent use.
+func (b *polynomialBackoff) Reset() {
+	atomic.StoreUint64(&b.attempt, 0)
+}
+
+// bounds implements bounder.
+func (b *polynomialBackoff) bounds(_ bounds) bounds {
+	return bounds{
+		delay: b.delay,
+	}
+}
+
+// String returns a description of the backoff.
+func (b *polynomialBackoff) String() string {
+	return "Polynomial(" + b.base.String() + ", " + strconv.FormatFloat(b.exponent, 'g', -1, 64) + ")"
+}
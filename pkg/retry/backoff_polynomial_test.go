@@ -0,0 +1,159 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPolynomialBackoff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		base     time.Duration
+		exponent float64
+		tries    int
+		exp      []time.Duration
+	}{
+		{
+			name:     "single",
+			base:     1 * time.Nanosecond,
+			exponent: 2,
+			tries:    1,
+			exp: []time.Duration{
+				1 * time.Nanosecond,
+			},
+		},
+		{
+			name:     "square",
+			base:     1 * time.Second,
+			exponent: 2,
+			tries:    5,
+			exp: []time.Duration{
+				1 * time.Second,
+				4 * time.Second,
+				9 * time.Second,
+				16 * time.Second,
+				25 * time.Second,
+			},
+		},
+		{
+			name:     "fractional",
+			base:     1 * time.Second,
+			exponent: 1.5,
+			tries:    5,
+			exp: []time.Duration{
+				1 * time.Second,
+				2828427124 * time.Nanosecond,
+				5196152422 * time.Nanosecond,
+				8 * time.Second,
+				11180339887 * time.Nanosecond,
+			},
+		},
+		{
+			name:     "constant",
+			base:     1 * time.Second,
+			exponent: 0,
+			tries:    3,
+			exp: []time.Duration{
+				1 * time.Second,
+				1 * time.Second,
+				1 * time.Second,
+			},
+		},
+		{
+			name:     "overflow",
+			base:     math.MaxInt64 / 2,
+			exponent: 2,
+			tries:    3,
+			exp: []time.Duration{
+				math.MaxInt64 / 2,
+				math.MaxInt64,
+				math.MaxInt64,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewPolynomial(tc.base, tc.exponent)
+
+			resultsCh := make(chan time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				go func() {
+					r, _ := b.Next(nil)
+					resultsCh <- r
+				}()
+			}
+
+			results := make([]time.Duration, tc.tries)
+			for i := 0; i < tc.tries; i++ {
+				select {
+				case delay := <-resultsCh:
+					results[i] = delay
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout")
+				}
+			}
+			sort.Slice(results, func(i, j int) bool {
+				return results[i] < results[j]
+			})
+
+			if !reflect.DeepEqual(results, tc.exp) {
+				t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, tc.exp)
+			}
+		})
+	}
+}
+
+func TestPolynomialBackoffPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		base     time.Duration
+		exponent float64
+	}{
+		{name: "base", base: 0, exponent: 2},
+		{name: "exponent", base: 1 * time.Second, exponent: -1},
+		{name: "nan", base: 1 * time.Second, exponent: math.NaN()},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewPolynomial(tc.base, tc.exponent)
+		})
+	}
+}
+
+func ExampleNewPolynomial() {
+	b := NewPolynomial(1*time.Second, 2)
+
+	for i := 0; i < 5; i++ {
+		delay, _ := b.Next(nil)
+		fmt.Printf("%v\n", delay)
+	}
+	// Output:
+	// 1s
+	// 4s
+	// 9s
+	// 16s
+	// 25s
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
-	"strconv"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry/retrytest"
 )
 
 func ExampleBackoffFunc() {
@@ -38,6 +46,212 @@ func ExampleBackoffFunc() {
 	}
 }
 
+// ctxKeyBackoff is a context key used by testContextBackoff.
+type ctxKeyBackoff struct{}
+
+// testContextBackoff returns the delay stored in the context, if any, or 1s
+// otherwise.
+type testContextBackoff struct{}
+
+func (testContextBackoff) Next(err error) (time.Duration, error) {
+	return 1 * time.Second, err
+}
+
+func (testContextBackoff) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	if d, ok := ctx.Value(ctxKeyBackoff{}).(time.Duration); ok {
+		return d, err
+	}
+	return 1 * time.Second, err
+}
+
+func TestAsContextBackoff(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.WithValue(context.Background(), ctxKeyBackoff{}, 1*time.Millisecond)
+
+	t.Run("context_aware", func(t *testing.T) {
+		t.Parallel()
+
+		var b Backoff = testContextBackoff{}
+		cb := AsContextBackoff(b)
+		if cb != b {
+			t.Errorf("expected %v to be returned as is", cb)
+		}
+		if delay, _ := cb.NextContext(ctx, nil); delay != 1*time.Millisecond {
+			t.Errorf("expected %v to be %v", delay, 1*time.Millisecond)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		t.Parallel()
+
+		cb := AsContextBackoff(NewConstant(1 * time.Second))
+		if delay, _ := cb.NextContext(ctx, nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+		if delay, ok := Peek(cb, nil); !ok || delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+	})
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		// Do prefers NextContext, which returns a delay of 1ms instead of 1s
+		var attempts int
+		start := time.Now()
+		err := Do(ctx, WithMaxRetries(2, testContextBackoff{}), func(_ context.Context) error {
+			attempts++
+			return RetryableError(io.EOF)
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+		if elapsed := time.Since(start); elapsed >= 1*time.Second {
+			t.Errorf("expected %v to be less than %v", elapsed, 1*time.Second)
+		}
+	})
+}
+
+func TestPeek(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		backoff func() Backoff
+	}{
+		{
+			name:    "constant",
+			backoff: func() Backoff { return NewConstant(1 * time.Second) },
+		},
+		{
+			name:    "exponential",
+			backoff: func() Backoff { return NewExponential(1 * time.Second) },
+		},
+		{
+			name:    "exponential_from",
+			backoff: func() Backoff { return NewExponentialFrom(1*time.Second, 62) },
+		},
+		{
+			name:    "fibonacci",
+			backoff: func() Backoff { return NewFibonacci(1 * time.Second) },
+		},
+		{
+			name: "max_retries",
+			backoff: func() Backoff {
+				return WithMaxRetries(2, NewExponential(1*time.Second))
+			},
+		},
+		{
+			name: "selective_max_retries",
+			backoff: func() Backoff {
+				return WithSelectiveMaxRetries(2, func(error) bool { return true }, NewExponential(1*time.Second))
+			},
+		},
+		{
+			name: "attempt_overrides",
+			backoff: func() Backoff {
+				return WithAttemptOverrides(map[uint64]time.Duration{2: 1 * time.Minute}, NewExponential(1*time.Second))
+			},
+		},
+		{
+			name: "exponential_floor",
+			backoff: func() Backoff {
+				return WithExponentialFloor(3*time.Second, NewExponential(1*time.Second))
+			},
+		},
+		{
+			name: "capped",
+			backoff: func() Backoff {
+				return WithCappedDuration(3*time.Second, NewExponential(1*time.Second))
+			},
+		},
+		{
+			name: "retryable",
+			backoff: func() Backoff {
+				return WithRetryable(NewExponential(1 * time.Second))
+			},
+		},
+		{
+			name: "warmup",
+			backoff: func() Backoff {
+				return WithWarmup(1*time.Hour, NewExponential(1*time.Second), NewConstant(1*time.Second))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := tc.backoff()
+			err := RetryableError(io.EOF)
+			for i := 0; i < 4; i++ {
+				peeked, ok := Peek(b, err)
+				if !ok {
+					t.Fatalf("attempt %d: expected peek to be supported", i+1)
+				}
+				if again, _ := Peek(b, err); again != peeked {
+					t.Fatalf("attempt %d: expected %v to be %v, peek changed the state", i+1, again, peeked)
+				}
+
+				delay, _ := b.Next(err)
+				if delay != peeked {
+					t.Errorf("attempt %d: expected %v to be %v", i+1, delay, peeked)
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxRetries(3, BackoffFunc(func(err error) (time.Duration, error) {
+			return 1 * time.Second, err
+		}))
+		if _, ok := Peek(b, nil); ok {
+			t.Errorf("expected peek to be unsupported")
+		}
+	})
+
+	t.Run("reset_on_error_change", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithResetOnErrorChange(NewExponential(1 * time.Second))
+		b.Next(io.EOF)
+
+		if delay, ok := Peek(b, io.EOF); !ok || delay != 2*time.Second {
+			t.Errorf("expected %v to be %v", delay, 2*time.Second)
+		}
+		if _, ok := Peek(b, testTimeoutError{}); ok {
+			t.Errorf("expected peek to be unsupported")
+		}
+	})
+}
+
+func ExamplePeek() {
+	b := NewExponential(1 * time.Second)
+	b = WithMaxRetries(3, b)
+
+	for {
+		if delay, ok := Peek(b, nil); ok && !IsStopped(delay) {
+			fmt.Printf("next retry in %v\n", delay)
+		}
+		if delay, _ := b.Next(nil); IsStopped(delay) {
+			break
+		}
+	}
+	// Output:
+	// next retry in 1s
+	// next retry in 2s
+	// next retry in 4s
+}
+
 func TestWithJitter(t *testing.T) {
 	t.Parallel()
 
@@ -116,6 +330,116 @@ func TestWithJitterPercent(t *testing.T) {
 	}
 }
 
+func TestWithJitterSource(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		backoff  func(r *rand.Rand) Backoff
+		min, max time.Duration
+	}{
+		{
+			name: "jitter",
+			backoff: func(r *rand.Rand) Backoff {
+				return WithJitterSource(250*time.Millisecond, r, NewConstant(1*time.Second))
+			},
+			min: 750 * time.Millisecond,
+			max: 1250 * time.Millisecond,
+		},
+		{
+			name: "jitter_percent",
+			backoff: func(r *rand.Rand) Backoff {
+				return WithJitterPercentSource(5, r, NewConstant(1*time.Second))
+			},
+			min: 950 * time.Millisecond,
+			max: 1050 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b1 := tc.backoff(rand.New(rand.NewSource(42)))
+			b2 := tc.backoff(rand.New(rand.NewSource(42)))
+			var distinct bool
+			var prev time.Duration
+			for i := 0; i < 1000; i++ {
+				delay1, _ := b1.Next(nil)
+				delay2, _ := b2.Next(nil)
+				if delay1 != delay2 {
+					t.Fatalf("attempt %d: expected %v to be %v", i+1, delay1, delay2)
+				}
+				if delay1 < tc.min || delay1 > tc.max {
+					t.Errorf("expected %v to be between %v and %v", delay1, tc.min, tc.max)
+				}
+				if i > 0 && delay1 != prev {
+					distinct = true
+				}
+				prev = delay1
+			}
+			if !distinct {
+				t.Errorf("expected jitter to vary")
+			}
+		})
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithJitterSource(250*time.Millisecond, rand.New(rand.NewSource(42)), NewConstant(1*time.Second))
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					b.Next(nil)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestWithJitterCap(t *testing.T) {
+	t.Parallel()
+
+	var minSeen, maxSeen time.Duration = math.MaxInt64, 0
+	for i := 0; i < 10_000; i++ {
+		b := WithJitterCap(100*time.Millisecond, 1*time.Second, NewConstant(1*time.Hour))
+		delay, _ := b.Next(nil)
+		if IsStopped(delay) {
+			t.Errorf("should not stop")
+		}
+
+		if min, max := 1*time.Hour-100*time.Millisecond, 1*time.Hour+100*time.Millisecond; delay < min || delay > max {
+			t.Errorf("expected %v to be between %v and %v", delay, min, max)
+		}
+		if delay < minSeen {
+			minSeen = delay
+		}
+		if delay > maxSeen {
+			maxSeen = delay
+		}
+	}
+
+	// most of the jitter is clamped
+	if minSeen != 1*time.Hour-100*time.Millisecond || maxSeen != 1*time.Hour+100*time.Millisecond {
+		t.Errorf("expected %v and %v to be the range ends", minSeen, maxSeen)
+	}
+
+	// never negative
+	b := WithJitterCap(1*time.Second, 1*time.Second, NewConstant(1*time.Nanosecond))
+	for i := 0; i < 1000; i++ {
+		if delay, _ := b.Next(nil); delay < 0 {
+			t.Fatalf("expected %v to be >= 0", delay)
+		}
+	}
+}
+
 func ExampleWithJitterPercent() {
 	ctx := context.Background()
 
@@ -130,6 +454,158 @@ func ExampleWithJitterPercent() {
 	}
 }
 
+func TestWithDecayingJitter(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 1000; i++ {
+		b := WithDecayingJitter(0.5, 0.5, NewConstant(1*time.Second))
+
+		fraction := 0.5
+		for attempt := 0; attempt < 5; attempt++ {
+			delay, _ := b.Next(nil)
+			if IsStopped(delay) {
+				t.Fatalf("should not stop")
+			}
+
+			min := time.Duration(float64(time.Second) * (1 - fraction))
+			max := time.Duration(float64(time.Second) * (1 + fraction))
+			if delay < min || delay > max {
+				t.Fatalf("attempt %d: expected %v to be between %v and %v", attempt+1, delay, min, max)
+			}
+			fraction *= 0.5
+		}
+	}
+}
+
+func TestWithAdditiveRandom(t *testing.T) {
+	t.Parallel()
+
+	var minSeen, maxSeen time.Duration = math.MaxInt64, 0
+	for i := 0; i < 10_000; i++ {
+		b := WithAdditiveRandom(10*time.Nanosecond, NewConstant(1*time.Second))
+		delay, _ := b.Next(nil)
+		if IsStopped(delay) {
+			t.Errorf("should not stop")
+		}
+
+		if min, max := 1*time.Second, 1*time.Second+10*time.Nanosecond; delay < min || delay > max {
+			t.Errorf("expected %v to be between %v and %v", delay, min, max)
+		}
+		if delay < minSeen {
+			minSeen = delay
+		}
+		if delay > maxSeen {
+			maxSeen = delay
+		}
+	}
+
+	// both ends of the range are included
+	if minSeen != 1*time.Second || maxSeen != 1*time.Second+10*time.Nanosecond {
+		t.Errorf("expected %v and %v to be the range ends", minSeen, maxSeen)
+	}
+}
+
+func ExampleWithAdditiveRandom() {
+	ctx := context.Background()
+
+	// Wait 1s plus up to 500ms
+	b := NewConstant(1 * time.Second)
+	b = WithAdditiveRandom(500*time.Millisecond, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithGroupSpread(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		base   time.Duration
+		size   int
+		index  int
+		spread time.Duration
+		exp    time.Duration
+	}{
+		{
+			name:   "first",
+			base:   1 * time.Second,
+			size:   4,
+			index:  0,
+			spread: 1 * time.Second,
+			exp:    1 * time.Second,
+		},
+		{
+			name:   "middle",
+			base:   1 * time.Second,
+			size:   4,
+			index:  2,
+			spread: 1 * time.Second,
+			exp:    1500 * time.Millisecond,
+		},
+		{
+			name:   "last",
+			base:   1 * time.Second,
+			size:   4,
+			index:  3,
+			spread: 1 * time.Second,
+			exp:    1750 * time.Millisecond,
+		},
+		{
+			name:   "overflow",
+			base:   math.MaxInt64 / 2,
+			size:   2,
+			index:  1,
+			spread: math.MaxInt64,
+			exp:    math.MaxInt64,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithGroupSpread(tc.size, tc.index, tc.spread, NewConstant(tc.base))
+			for i := 0; i < 3; i++ {
+				delay, _ := b.Next(nil)
+				if delay != tc.exp {
+					t.Errorf("attempt %d: expected %v to be %v", i+1, delay, tc.exp)
+				}
+			}
+		})
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic")
+		}
+	}()
+	WithGroupSpread(4, 4, 1*time.Second, NewConstant(1*time.Second))
+}
+
+func ExampleWithGroupSpread() {
+	ctx := context.Background()
+
+	// e.g. the ordinal of a pod of a StatefulSet with 5 replicas
+	index := 2
+
+	b := NewConstant(1 * time.Minute)
+	b = WithGroupSpread(5, index, 30*time.Second, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
 func TestWithMaxRetries(t *testing.T) {
 	t.Parallel()
 
@@ -169,27 +645,59 @@ func ExampleWithMaxRetries() {
 	}
 }
 
-func TestWithCappedDuration(t *testing.T) {
+func TestWithMaxAttempts(t *testing.T) {
 	t.Parallel()
 
-	b := WithCappedDuration(3*time.Second, BackoffFunc(func(err error) (time.Duration, error) {
-		return 5 * time.Second, err
-	}))
-
-	delay, _ := b.Next(nil)
-	if IsStopped(delay) {
-		t.Errorf("should not stop")
+	cases := []struct {
+		name     string
+		max      uint64
+		attempts int
+	}{
+		{
+			name:     "zero",
+			max:      0,
+			attempts: 1,
+		},
+		{
+			name:     "one",
+			max:      1,
+			attempts: 1,
+		},
+		{
+			name:     "three",
+			max:      3,
+			attempts: 3,
+		},
 	}
-	if delay != 3*time.Second {
-		t.Errorf("expected %v to be %v", delay, 3*time.Second)
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithMaxAttempts(tc.max, NewConstant(1*time.Nanosecond))
+
+			var attempts int
+			err := Do(context.Background(), b, func(_ context.Context) error {
+				attempts++
+				return RetryableError(io.EOF)
+			})
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("expected %v to be %v", err, io.EOF)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
 	}
 }
 
-func ExampleWithCappedDuration() {
+func ExampleWithMaxAttempts() {
 	ctx := context.Background()
 
 	b := NewFibonacci(1 * time.Second)
-	b = WithCappedDuration(3*time.Second, b)
+	b = WithMaxAttempts(3, b)
 
 	if err := Do(ctx, b, func(_ context.Context) error {
 		// TODO: logic here
@@ -199,49 +707,173 @@ func ExampleWithCappedDuration() {
 	}
 }
 
-func TestWithMaxDuration(t *testing.T) {
+func TestWithMaxConsecutiveRetries(t *testing.T) {
 	t.Parallel()
 
-	b := WithMaxDuration(250*time.Millisecond, BackoffFunc(func(err error) (time.Duration, error) {
-		return 1 * time.Second, err
-	}))
+	// each poll fails twice, then succeeds
+	poll := func(b Backoff) error {
+		var i int
+		return Do(context.Background(), b, func(_ context.Context) error {
+			i++
+			if i <= 2 {
+				return io.EOF
+			}
+			return nil
+		})
+	}
 
-	// Take once, within timeout.
-	delay, _ := b.Next(nil)
-	if IsStopped(delay) {
-		t.Error("should not stop")
+	cases := []struct {
+		name     string
+		b        Backoff
+		failedAt int
+	}{
+		{
+			name:     "consecutive",
+			b:        WithMaxConsecutiveRetries(2, NewConstant(1*time.Nanosecond)),
+			failedAt: -1,
+		},
+		{
+			name:     "total",
+			b:        WithMaxRetries(2, NewConstant(1*time.Nanosecond)),
+			failedAt: 1,
+		},
 	}
 
-	if delay > 250*time.Millisecond {
-		t.Errorf("expected %v to be less than %v", delay, 250*time.Millisecond)
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			failedAt := -1
+			for i := 0; i < 5; i++ {
+				if err := poll(tc.b); err != nil {
+					failedAt = i
+					break
+				}
+			}
+			if failedAt != tc.failedAt {
+				t.Errorf("expected %v to be %v", failedAt, tc.failedAt)
+			}
+		})
 	}
 
-	time.Sleep(200 * time.Millisecond)
+	t.Run("summary", func(t *testing.T) {
+		t.Parallel()
 
-	// Take again, remainder contines
-	delay, _ = b.Next(nil)
-	if IsStopped(delay) {
-		t.Error("should not stop")
+		// the successes are observed through the adapter of DoSummary as well
+		b := WithMaxConsecutiveRetries(1, NewConstant(1*time.Nanosecond))
+		for i := 0; i < 5; i++ {
+			var calls int
+			_, err := DoSummary(context.Background(), b, func(_ context.Context) error {
+				calls++
+				if calls == 1 {
+					return io.EOF
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("poll %d: expected %v to be nil", i+1, err)
+			}
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxConsecutiveRetries(2, NewConstant(1*time.Nanosecond))
+		var calls int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			calls++
+			return io.EOF
+		})
+		if err != io.EOF {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if got, want := calls, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := Describe(b), "WithMaxConsecutiveRetries(2) -> Constant(1ns)"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+}
+
+func TestWithSelectiveMaxRetries(t *testing.T) {
+	t.Parallel()
+
+	errThrottled := errors.New("throttled")
+	b := WithSelectiveMaxRetries(2, func(err error) bool {
+		return !errors.Is(err, errThrottled)
+	}, NewConstant(1*time.Second))
+
+	errs := []error{errThrottled, io.EOF, errThrottled, errThrottled, io.EOF, errThrottled, io.EOF}
+	stops := []bool{false, false, false, false, false, false, true}
+	for i, err := range errs {
+		delay, _ := b.Next(err)
+		if IsStopped(delay) != stops[i] {
+			t.Errorf("attempt %d: expected stop to be %v, got delay %v", i+1, stops[i], delay)
+		}
 	}
+}
+
+func TestWithMaxRetriesContextOverride(t *testing.T) {
+	t.Parallel()
 
-	if delay > 50*time.Millisecond {
-		t.Errorf("expected %v to be less than %v", delay, 50*time.Millisecond)
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		retries int
+	}{
+		{
+			name:    "absent",
+			ctx:     context.Background(),
+			retries: 3,
+		},
+		{
+			name:    "tighter",
+			ctx:     ContextWithMaxRetries(context.Background(), 1),
+			retries: 1,
+		},
+		{
+			name:    "looser",
+			ctx:     ContextWithMaxRetries(context.Background(), 10),
+			retries: 3,
+		},
+		{
+			name:    "zero",
+			ctx:     ContextWithMaxRetries(context.Background(), 0),
+			retries: 0,
+		},
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	for _, tc := range cases {
+		tc := tc
 
-	// Now we stop
-	delay, _ = b.Next(nil)
-	if delay >= 0 {
-		t.Errorf("should stop")
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithMaxRetriesContextOverride(3, NewConstant(1*time.Nanosecond))
+
+			var attempts int
+			_ = Do(tc.ctx, b, func(_ context.Context) error {
+				attempts++
+				return fmt.Errorf("oops")
+			})
+			if got, want := attempts, tc.retries+1; got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		})
 	}
 }
 
-func ExampleWithMaxDuration() {
-	ctx := context.Background()
+func ExampleWithMaxRetriesContextOverride() {
+	// The caller requests to fail fast ...
+	ctx := ContextWithMaxRetries(context.Background(), 1)
 
-	b := NewFibonacci(1 * time.Second)
-	b = WithMaxDuration(5*time.Second, b)
+	// ... while the server allows at most 5 retries.
+	b := NewExponential(100 * time.Millisecond)
+	b = WithMaxRetriesContextOverride(5, b)
 
 	if err := Do(ctx, b, func(_ context.Context) error {
 		// TODO: logic here
@@ -251,18 +883,1407 @@ func ExampleWithMaxDuration() {
 	}
 }
 
-type httpRetryableError struct {
-	err  error
-	resp http.Response
-}
+func TestWithAttemptOverrides(t *testing.T) {
+	t.Parallel()
 
-func (e *httpRetryableError) Unwrap() error {
-	return e.err
-}
+	b := WithAttemptOverrides(map[uint64]time.Duration{
+		3: 10 * time.Second,
+	}, WithMaxRetries(4, BackoffFunc(func(err error) (time.Duration, error) {
+		return 1 * time.Second, err
+	})))
 
-func (e *httpRetryableError) Error() string {
-	return e.err.Error()
-}
+	exp := []time.Duration{1 * time.Second, 1 * time.Second, 10 * time.Second, 1 * time.Second}
+	for i, want := range exp {
+		delay, _ := b.Next(nil)
+		if delay != want {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, delay, want)
+		}
+	}
+
+	// The stop of the next backoff is never overridden
+	delay, _ := b.Next(nil)
+	if !IsStopped(delay) {
+		t.Errorf("should stop")
+	}
+}
+
+func ExampleWithAttemptOverrides() {
+	ctx := context.Background()
+
+	b := NewConstant(1 * time.Second)
+	// Wait 30s before the 3rd retry
+	b = WithAttemptOverrides(map[uint64]time.Duration{3: 30 * time.Second}, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string { return "timeout" }
+
+func TestWithResetOnErrorChange(t *testing.T) {
+	t.Parallel()
+
+	b := WithResetOnErrorChange(NewExponential(1 * time.Second))
+
+	errs := []error{
+		testTimeoutError{},
+		fmt.Errorf("wrapped: %w", testTimeoutError{}), // same root cause
+		testTimeoutError{},
+		io.EOF, // new error type resets
+		io.ErrUnexpectedEOF,
+		testTimeoutError{}, // new error type resets
+	}
+	exp := []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		1 * time.Second,
+		2 * time.Second,
+		1 * time.Second,
+	}
+
+	for i, err := range errs {
+		delay, _ := b.Next(err)
+		if delay != exp[i] {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, delay, exp[i])
+		}
+	}
+}
+
+func TestWithDeadlineResetDetector(t *testing.T) {
+	t.Parallel()
+
+	b := WithDeadlineResetDetector(NewExponential(1 * time.Second)).(ContextBackoff)
+
+	now := time.Now()
+	ctx1, cancel1 := context.WithDeadline(context.Background(), now.Add(1*time.Hour))
+	defer cancel1()
+	ctx2, cancel2 := context.WithDeadline(context.Background(), now.Add(2*time.Hour))
+	defer cancel2()
+
+	ctxs := []context.Context{
+		ctx1,
+		ctx1,
+		context.Background(), // no deadline
+		ctx2,                 // extended deadline resets
+		ctx2,
+		ctx1, // shortened deadline
+	}
+	exp := []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+	}
+
+	for i, ctx := range ctxs {
+		delay, _ := b.NextContext(ctx, nil)
+		if delay != exp[i] {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, delay, exp[i])
+		}
+	}
+}
+
+func TestWithResetAfter(t *testing.T) {
+	t.Parallel()
+
+	b := WithResetAfter(50*time.Millisecond, NewExponential(1*time.Second))
+
+	next := func(exp ...time.Duration) {
+		t.Helper()
+		for i, e := range exp {
+			if delay, _ := b.Next(nil); delay != e {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, e)
+			}
+		}
+	}
+
+	next(1*time.Second, 2*time.Second, 4*time.Second)
+
+	// quiet period restarts the sequence
+	time.Sleep(60 * time.Millisecond)
+	next(1*time.Second, 2*time.Second)
+
+	// peeking does not reset
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := Peek(b, nil); ok {
+		t.Error("expected peek to be unsupported")
+	}
+	next(1*time.Second, 2*time.Second)
+}
+
+func TestWithResetOn(t *testing.T) {
+	t.Parallel()
+
+	errLeader := errors.New("leader changed")
+	b := WithResetOn(func(err error) bool {
+		return errors.Is(err, errLeader)
+	}, NewExponential(1*time.Second))
+
+	next := func(err error, exp ...time.Duration) {
+		t.Helper()
+		for i, e := range exp {
+			if delay, _ := b.Next(err); delay != e {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, e)
+			}
+		}
+	}
+
+	oops := errors.New("oops")
+	next(oops, 1*time.Second, 2*time.Second, 4*time.Second)
+
+	// matching error restarts the sequence
+	next(RetryableError(errLeader), 1*time.Second)
+	next(oops, 2*time.Second, 4*time.Second)
+
+	// peeking does not reset
+	if _, ok := Peek(b, errLeader); ok {
+		t.Error("expected peek to be unsupported")
+	}
+	next(oops, 8*time.Second)
+
+	// error is passed through
+	if _, err := b.Next(errLeader); err != errLeader {
+		t.Errorf("expected %v to be %v", err, errLeader)
+	}
+
+	// resetting the inner backoff lifts its limits
+	if _, ok := MaxTotalTime(WithResetOn(func(error) bool { return true }, WithMaxRetries(3, NewConstant(1*time.Second)))); ok {
+		t.Error("expected unbounded total time")
+	}
+}
+
+func TestWithExponentialFloor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ramp_up", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithExponentialFloor(10*time.Millisecond, NewExponential(1*time.Millisecond))
+
+		exp := []time.Duration{
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+			16 * time.Millisecond,
+			32 * time.Millisecond,
+		}
+		for i, want := range exp {
+			delay, _ := b.Next(nil)
+			if delay != want {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, want)
+			}
+		}
+	})
+
+	t.Run("only_ramp_up", func(t *testing.T) {
+		t.Parallel()
+
+		delays := []time.Duration{1 * time.Second, 5 * time.Second, 1 * time.Second}
+		var i int
+		b := WithExponentialFloor(2*time.Second, BackoffFunc(func(err error) (time.Duration, error) {
+			d := delays[i]
+			i++
+			return d, err
+		}))
+
+		exp := []time.Duration{2 * time.Second, 5 * time.Second, 1 * time.Second}
+		for i, want := range exp {
+			delay, _ := b.Next(nil)
+			if delay != want {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, want)
+			}
+		}
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithCappedDuration(5*time.Millisecond,
+			WithExponentialFloor(10*time.Millisecond, NewExponential(1*time.Millisecond)))
+
+		for i := 0; i < 10; i++ {
+			delay, _ := b.Next(nil)
+			if delay != 5*time.Millisecond {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, 5*time.Millisecond)
+			}
+		}
+	})
+}
+
+func TestWithScale(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		factor float64
+		exp    time.Duration
+	}{
+		{
+			name:   "noop",
+			factor: 1,
+			exp:    2 * time.Second,
+		},
+		{
+			name:   "relax",
+			factor: 3,
+			exp:    6 * time.Second,
+		},
+		{
+			name:   "tighten",
+			factor: 0.25,
+			exp:    500 * time.Millisecond,
+		},
+		{
+			name:   "negative",
+			factor: -1,
+			exp:    0,
+		},
+		{
+			name:   "overflow",
+			factor: math.MaxInt64,
+			exp:    math.MaxInt64,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithScale(func() float64 { return tc.factor }, NewConstant(2*time.Second))
+			delay, _ := b.Next(nil)
+			if delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithScale(func() float64 { return 2 }, BackoffFunc(func(err error) (time.Duration, error) {
+			return Stop, err
+		}))
+		delay, _ := b.Next(nil)
+		if !IsStopped(delay) {
+			t.Errorf("should stop")
+		}
+	})
+}
+
+func ExampleWithScale() {
+	ctx := context.Background()
+
+	// The factor could be changed at runtime, e.g. by an admin endpoint.
+	var factor uint64 = math.Float64bits(1)
+
+	b := NewFibonacci(1 * time.Second)
+	b = WithScale(func() float64 {
+		return math.Float64frombits(atomic.LoadUint64(&factor))
+	}, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithQuantize(t *testing.T) {
+	t.Parallel()
+
+	buckets := []time.Duration{5 * time.Second, 1 * time.Second, 2500 * time.Millisecond}
+
+	cases := []struct {
+		name  string
+		delay time.Duration
+		exp   time.Duration
+	}{
+		{name: "zero", delay: 0, exp: 0},
+		{name: "below", delay: 100 * time.Millisecond, exp: 1 * time.Second},
+		{name: "boundary", delay: 2500 * time.Millisecond, exp: 2500 * time.Millisecond},
+		{name: "between", delay: 3 * time.Second, exp: 5 * time.Second},
+		{name: "above", delay: 7 * time.Second, exp: 7 * time.Second},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithQuantize(buckets, BackoffFunc(func(err error) (time.Duration, error) {
+				return tc.delay, err
+			}))
+			if delay, _ := b.Next(nil); delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+
+	t.Run("bounds", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxRetries(2, WithQuantize(buckets, NewExponential(1*time.Second)))
+		if got, want := Describe(b), "WithMaxRetries(2) -> WithQuantize([1s 2.5s 5s]) -> Exponential(1s)"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+		if total, ok := MaxTotalTime(b); !ok || total != 3500*time.Millisecond {
+			t.Errorf("expected %v, %v to be %v", total, ok, 3500*time.Millisecond)
+		}
+	})
+}
+
+func TestWithCappedDuration(t *testing.T) {
+	t.Parallel()
+
+	b := WithCappedDuration(3*time.Second, BackoffFunc(func(err error) (time.Duration, error) {
+		return 5 * time.Second, err
+	}))
+
+	delay, _ := b.Next(nil)
+	if IsStopped(delay) {
+		t.Errorf("should not stop")
+	}
+	if delay != 3*time.Second {
+		t.Errorf("expected %v to be %v", delay, 3*time.Second)
+	}
+}
+
+func ExampleWithCappedDuration() {
+	ctx := context.Background()
+
+	b := NewFibonacci(1 * time.Second)
+	b = WithCappedDuration(3*time.Second, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithScheduleWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeOfDay := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	day := 24 * time.Hour
+	later := Window{Start: (timeOfDay + 1*time.Hour) % day, End: (timeOfDay + 2*time.Hour) % day, Multiplier: 10}
+	current := Window{Start: (timeOfDay + day - 1*time.Hour) % day, End: (timeOfDay + 1*time.Hour) % day}
+
+	cases := []struct {
+		name    string
+		windows []Window
+		exp     time.Duration
+	}{
+		{
+			name:    "none",
+			windows: nil,
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "outside",
+			windows: []Window{later},
+			exp:     1 * time.Second,
+		},
+		{
+			name: "multiplier",
+			windows: []Window{
+				later,
+				{Start: current.Start, End: current.End, Multiplier: 2},
+			},
+			exp: 2 * time.Second,
+		},
+		{
+			name: "cap",
+			windows: []Window{
+				{Start: current.Start, End: current.End, Multiplier: 10, Cap: 5 * time.Second},
+			},
+			exp: 5 * time.Second,
+		},
+		{
+			name: "first",
+			windows: []Window{
+				{Start: 0, End: 0, Multiplier: 3},
+				{Start: current.Start, End: current.End, Multiplier: 2},
+			},
+			exp: 3 * time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithScheduleWindow(tc.windows, NewConstant(1*time.Second))
+			if delay, _ := b.Next(nil); delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+}
+
+func ExampleWithScheduleWindow() {
+	ctx := context.Background()
+
+	// retry gently during business hours
+	b := NewExponential(1 * time.Second)
+	b = WithScheduleWindow([]Window{
+		{Start: 9 * time.Hour, End: 17 * time.Hour, Multiplier: 4, Cap: 10 * time.Minute},
+	}, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithDelayFunc(t *testing.T) {
+	t.Parallel()
+
+	b := WithDelayFunc(func(d time.Duration, err error) time.Duration {
+		if errors.Is(err, io.EOF) {
+			return Stop
+		}
+		return d.Round(time.Second) + 5*time.Second
+	}, WithMaxRetries(2, NewConstant(1400*time.Millisecond)))
+
+	cases := []struct {
+		err error
+		exp time.Duration
+	}{
+		{err: io.ErrUnexpectedEOF, exp: 6 * time.Second},
+		{err: io.EOF, exp: Stop},
+		{err: io.ErrUnexpectedEOF, exp: Stop}, // the next backoff stops
+	}
+	for i, tc := range cases {
+		if delay, _ := b.Next(tc.err); delay != tc.exp {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, delay, tc.exp)
+		}
+	}
+}
+
+func ExampleWithDelayFunc() {
+	b := NewConstant(1500 * time.Millisecond)
+
+	// round the delays to full seconds
+	b = WithDelayFunc(func(d time.Duration, _ error) time.Duration {
+		return d.Round(time.Second)
+	}, b)
+
+	delay, _ := b.Next(nil)
+	fmt.Println(delay)
+	// Output:
+	// 2s
+}
+
+func TestWithSampledGiveUp(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		rate float64
+		min  int
+		max  int
+	}{
+		{name: "never", rate: 0, min: 0, max: 0},
+		{name: "always", rate: 1, min: 1000, max: 1000},
+		{name: "sampled", rate: 0.1, min: 50, max: 150},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			for i := 0; i < 1000; i++ {
+				b := WithSampledGiveUp(tc.rate, func(err error) {
+					if err != io.EOF {
+						t.Errorf("expected %v to be %v", err, io.EOF)
+					}
+					calls++
+				}, WithMaxRetries(1, NewConstant(1*time.Second)))
+
+				// the first call does not give up
+				b.Next(io.EOF)
+				b.Next(io.EOF)
+			}
+
+			if calls < tc.min || calls > tc.max {
+				t.Errorf("expected %v to be between %v and %v", calls, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestWithBand(t *testing.T) {
+	t.Parallel()
+
+	t.Run("clamps", func(t *testing.T) {
+		t.Parallel()
+
+		delays := []time.Duration{0, 1 * time.Second, 3 * time.Second, 10 * time.Second, Stop}
+		var i int
+		b := WithBand(2*time.Second, 5*time.Second, BackoffFunc(func(err error) (time.Duration, error) {
+			delay := delays[i]
+			i++
+			return delay, err
+		}))
+
+		exp := []time.Duration{2 * time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, Stop}
+		for i, want := range exp {
+			delay, _ := b.Next(nil)
+			if delay != want {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, delay, want)
+			}
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		t.Parallel()
+
+		min, max := 900*time.Millisecond, 1100*time.Millisecond
+		b := WithBand(min, max, WithJitter(500*time.Millisecond, false, NewConstant(1*time.Second)))
+		for i := 0; i < 1000; i++ {
+			delay, _ := b.Next(nil)
+			if delay < min || delay > max {
+				t.Fatalf("expected %v to be within [%v, %v]", delay, min, max)
+			}
+		}
+	})
+}
+
+func TestWithSoftCap(t *testing.T) {
+	t.Parallel()
+
+	b := WithSoftCap(10*time.Second, 2*time.Second, NewExponential(1*time.Second))
+
+	// below the target, delays are unchanged
+	for _, want := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		delay, _ := b.Next(nil)
+		if delay != want {
+			t.Errorf("expected %v to be %v", delay, want)
+		}
+	}
+
+	// above the target, delays are spread around the target
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 1_000; i++ {
+		delay, _ := b.Next(nil)
+		if min, max := 8*time.Second, 12*time.Second; delay < min || delay > max {
+			t.Errorf("expected %v to be between %v and %v", delay, min, max)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected delays to be spread, got %v", seen)
+	}
+}
+
+func ExampleWithSoftCap() {
+	ctx := context.Background()
+
+	b := NewExponential(1 * time.Second)
+	// Delays above 30s become something between 25s and 35s
+	b = WithSoftCap(30*time.Second, 5*time.Second, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithMaxDuration(t *testing.T) {
+	c := retrytest.NewFakeClock(time.Now())
+	SetClock(c)
+	defer SetClock(nil)
+
+	b := WithMaxDuration(250*time.Millisecond, BackoffFunc(func(err error) (time.Duration, error) {
+		return 1 * time.Second, err
+	}))
+
+	// Take once, within timeout.
+	delay, _ := b.Next(nil)
+	if delay != 250*time.Millisecond {
+		t.Errorf("expected %v to be %v", delay, 250*time.Millisecond)
+	}
+
+	c.Advance(200 * time.Millisecond)
+
+	// Take again, remainder contines
+	delay, _ = b.Next(nil)
+	if delay != 50*time.Millisecond {
+		t.Errorf("expected %v to be %v", delay, 50*time.Millisecond)
+	}
+
+	c.Advance(50 * time.Millisecond)
+
+	// Now we stop
+	delay, _ = b.Next(nil)
+	if !IsStopped(delay) {
+		t.Errorf("should stop")
+	}
+
+	// Reset starts over
+	b.(Resettable).Reset()
+	delay, _ = b.Next(nil)
+	if delay != 250*time.Millisecond {
+		t.Errorf("expected %v to be %v", delay, 250*time.Millisecond)
+	}
+}
+
+func TestWithMaxDurationContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	b := WithMaxDuration(1*time.Hour, NewConstant(1*time.Minute)).(ContextBackoff)
+
+	t.Run("no_deadline", func(t *testing.T) {
+		delay, _ := b.NextContext(context.Background(), nil)
+		if delay != 1*time.Minute {
+			t.Errorf("expected %v to be %v", delay, 1*time.Minute)
+		}
+	})
+
+	t.Run("clamped", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		delay, _ := b.NextContext(ctx, nil)
+		if delay <= 0 || delay > 1*time.Second {
+			t.Errorf("expected %v to be within (0, %v]", delay, 1*time.Second)
+		}
+	})
+
+	t.Run("passed", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-1*time.Second))
+		defer cancel()
+
+		delay, _ := b.NextContext(ctx, nil)
+		if !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	})
+}
+
+func TestWithContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	b := WithContextDeadline(NewConstant(1 * time.Second)).(ContextBackoff)
+
+	t.Run("no_deadline", func(t *testing.T) {
+		t.Parallel()
+
+		if delay, _ := b.NextContext(context.Background(), nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+		if delay, _ := b.Next(nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+	})
+
+	t.Run("clamped", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		if delay, _ := b.NextContext(ctx, nil); delay <= 0 || delay > 100*time.Millisecond {
+			t.Errorf("expected %v to be within (0, %v]", delay, 100*time.Millisecond)
+		}
+	})
+
+	t.Run("passed", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-1*time.Second))
+		defer cancel()
+
+		if delay, _ := b.NextContext(ctx, nil); !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	})
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := Do(ctx, b, func(_ context.Context) error {
+			return io.EOF
+		})
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v or %v", err, context.DeadlineExceeded, io.EOF)
+		}
+		if elapsed := time.Since(start); elapsed >= 1*time.Second {
+			t.Errorf("expected %v to be less than %v", elapsed, 1*time.Second)
+		}
+	})
+}
+
+func ExampleWithMaxDuration() {
+	ctx := context.Background()
+
+	b := NewFibonacci(1 * time.Second)
+	b = WithMaxDuration(5*time.Second, b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestNewMaxDurationTimer(t *testing.T) {
+	t.Parallel()
+
+	b := NewMaxDurationTimer(100*time.Millisecond, NewConstant(1*time.Second))
+
+	delay, _ := b.Next(nil)
+	if IsStopped(delay) {
+		t.Error("should not stop")
+	}
+	if delay > 100*time.Millisecond {
+		t.Errorf("expected %v to be less than %v", delay, 100*time.Millisecond)
+	}
+
+	select {
+	case <-b.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	// stops immediately once fired
+	delay, _ = b.Next(nil)
+	if !IsStopped(delay) {
+		t.Errorf("should stop")
+	}
+
+	if got, want := Describe(b), "MaxDurationTimer(100ms) -> Constant(1s)"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if total, ok := MaxTotalTime(b); !ok || total != 100*time.Millisecond {
+		t.Errorf("expected %v, %v to be %v", total, ok, 100*time.Millisecond)
+	}
+	if b.Stop() {
+		t.Error("expected the fired timer not to be stopped")
+	}
+}
+
+func TestMaxDurationTimerStop(t *testing.T) {
+	t.Parallel()
+
+	b := NewMaxDurationTimer(50*time.Millisecond, NewConstant(1*time.Second))
+	if !b.Stop() {
+		t.Fatal("expected the timer to be stopped")
+	}
+
+	// the duration is still enforced, but Done is never closed
+	time.Sleep(60 * time.Millisecond)
+	if delay, _ := b.Next(nil); !IsStopped(delay) {
+		t.Errorf("should stop")
+	}
+	select {
+	case <-b.Done():
+		t.Error("expected Done not to be closed")
+	default:
+	}
+}
+
+func ExampleNewMaxDurationTimer() {
+	ctx := context.Background()
+
+	b := NewMaxDurationTimer(30*time.Second, NewExponential(1*time.Second))
+	defer b.Stop()
+	go func() {
+		<-b.Done()
+		// TODO: signal the exhausted budget, e.g. to stop accepting new work
+	}()
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithWarmup(t *testing.T) {
+	t.Parallel()
+
+	b := WithWarmup(100*time.Millisecond, NewConstant(1*time.Second), NewConstant(5*time.Second))
+
+	delay, _ := b.Next(nil)
+	if got, want := delay, 1*time.Second; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	delay, _ = b.Next(nil)
+	if got, want := delay, 5*time.Second; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+
+	if got, want := Describe(b), "WithWarmup(100ms, Constant(1s), Constant(5s))"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func ExampleWithWarmup() {
+	ctx := context.Background()
+
+	// Retry patiently within the first 5 minutes after start, e.g. while other
+	// services are still deploying.
+	warm := WithMaxDuration(1*time.Minute, NewConstant(1*time.Second))
+	cold := WithMaxRetries(3, NewExponential(100*time.Millisecond))
+	b := WithWarmup(5*time.Minute, warm, cold)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestWithTreatAsSuccess(t *testing.T) {
+	t.Parallel()
+
+	errAlreadyExists := errors.New("already exists")
+	errUnavailable := errors.New("unavailable")
+
+	// create-if-not-exists: the first attempt creates the resource, but its
+	// response is lost; the retry then finds it already existing
+	var created bool
+	var calls int
+	create := func(_ context.Context) error {
+		calls++
+		if created {
+			return fmt.Errorf("create: %w", errAlreadyExists)
+		}
+		created = true
+		return RetryableError(errUnavailable)
+	}
+
+	b := WithTreatAsSuccess(WithRetryable(WithMaxRetries(3, NewConstant(1*time.Nanosecond))), errAlreadyExists)
+	if err := Do(context.Background(), b, create); err != nil {
+		t.Fatalf("expected no err, got %v", err)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+
+	// other errors are retried as usual
+	b = WithTreatAsSuccess(WithRetryable(WithMaxRetries(3, NewConstant(1*time.Nanosecond))), errAlreadyExists)
+	calls = 0
+	err := Do(context.Background(), b, func(_ context.Context) error {
+		calls++
+		return RetryableError(errUnavailable)
+	})
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("expected %v to be %v", err, errUnavailable)
+	}
+	if got, want := calls, 4; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+
+	if got, want := Describe(b), "WithTreatAsSuccess() -> WithRetryable() -> WithMaxRetries(3) -> Constant(1ns)"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestWithTreatAsSuccessHelpers(t *testing.T) {
+	t.Parallel()
+
+	errAlreadyExists := errors.New("already exists")
+
+	cases := []struct {
+		name string
+		do   func(ctx context.Context, b Backoff, f RetryFunc) error
+	}{
+		{
+			name: "Do",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				return Do(ctx, b, f)
+			},
+		},
+		{
+			name: "DoSummary",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				_, err := DoSummary(ctx, b, f)
+				return err
+			},
+		},
+		{
+			name: "DoValueSummary",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				_, _, err := DoValueSummary(ctx, b, func(ctx context.Context) (int, error) {
+					return 0, f(ctx)
+				})
+				return err
+			},
+		},
+		{
+			name: "DoValueIf",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				_, err := DoValueIf(ctx, b, func(error) bool { return true }, func(ctx context.Context) (int, error) {
+					return 0, f(ctx)
+				})
+				return err
+			},
+		},
+		{
+			name: "DoWorkBudget",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				return DoWorkBudget(ctx, b, 1*time.Hour, f)
+			},
+		},
+		{
+			name: "DoProgress",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				return DoProgress(ctx, b, func(ctx context.Context) (int64, error) {
+					return 0, f(ctx)
+				}, 1, 1)
+			},
+		},
+		{
+			name: "DoClassified",
+			do: func(ctx context.Context, b Backoff, f RetryFunc) error {
+				_, err := DoClassified(ctx, b, func(error) bool { return true }, f)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithTreatAsSuccess(WithMaxRetries(3, NewConstant(1*time.Nanosecond)), errAlreadyExists)
+			var calls int
+			err := tc.do(context.Background(), b, func(_ context.Context) error {
+				calls++
+				return errAlreadyExists
+			})
+			if err != nil {
+				t.Errorf("expected %v to be nil", err)
+			}
+			if got, want := calls, 1; got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		})
+	}
+}
+func TestWithStatusCode(t *testing.T) {
+	t.Parallel()
+
+	extract := func(err error) (int, bool) {
+		var herr *httpRetryableError
+		if !errors.As(err, &herr) {
+			return 0, false
+		}
+		return herr.resp.StatusCode, true
+	}
+	retryable := func(code int) bool {
+		return code == 429 || code >= 500
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		stop bool
+	}{
+		{
+			name: "retryable",
+			err:  &httpRetryableError{err: io.EOF, resp: http.Response{StatusCode: 503}},
+		},
+		{
+			name: "non_retryable",
+			err:  &httpRetryableError{err: io.EOF, resp: http.Response{StatusCode: 404}},
+			stop: true,
+		},
+		{
+			name: "no_code",
+			err:  io.EOF,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithStatusCode(extract, retryable, NewConstant(1*time.Second))
+			delay, err := b.Next(tc.err)
+			if IsStopped(delay) != tc.stop {
+				t.Errorf("expected stop to be %v, got delay %v", tc.stop, delay)
+			}
+			if err != tc.err {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+		})
+	}
+}
+
+type testMetadataError struct {
+	md map[string]string
+}
+
+func (e testMetadataError) Error() string {
+	return "metadata"
+}
+
+func TestWithDelayFromMetadata(t *testing.T) {
+	t.Parallel()
+
+	get := func(err error) (map[string]string, bool) {
+		var merr testMetadataError
+		if !errors.As(err, &merr) {
+			return nil, false
+		}
+		return merr.md, true
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		exp  time.Duration
+	}{
+		{
+			name: "no_metadata",
+			err:  io.EOF,
+			exp:  1 * time.Second,
+		},
+		{
+			name: "missing_key",
+			err:  testMetadataError{md: map[string]string{"other": "5s"}},
+			exp:  1 * time.Second,
+		},
+		{
+			name: "hint",
+			err:  fmt.Errorf("wrapped: %w", testMetadataError{md: map[string]string{"retry-delay": "5s"}}),
+			exp:  5 * time.Second,
+		},
+		{
+			name: "invalid",
+			err:  testMetadataError{md: map[string]string{"retry-delay": "soon"}},
+			exp:  1 * time.Second,
+		},
+		{
+			name: "negative",
+			err:  testMetadataError{md: map[string]string{"retry-delay": "-5s"}},
+			exp:  1 * time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithDelayFromMetadata(get, "retry-delay", time.ParseDuration, NewConstant(1*time.Second))
+			if delay, _ := b.Next(tc.err); delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+
+	t.Run("stops", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithDelayFromMetadata(get, "retry-delay", time.ParseDuration, WithMaxRetries(0, NewConstant(1*time.Second)))
+		err := testMetadataError{md: map[string]string{"retry-delay": "5s"}}
+		if delay, _ := b.Next(err); !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	})
+}
+
+type testRetryAfterError struct {
+	delay time.Duration
+	ok    bool
+}
+
+func (e testRetryAfterError) Error() string {
+	return "retry after"
+}
+
+func (e testRetryAfterError) RetryAfter() (time.Duration, bool) {
+	return e.delay, e.ok
+}
+
+func TestWithRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		exp  time.Duration
+	}{
+		{
+			name: "no_hint",
+			err:  io.EOF,
+			exp:  1 * time.Second,
+		},
+		{
+			name: "hint",
+			err:  fmt.Errorf("wrapped: %w", testRetryAfterError{delay: 5 * time.Second, ok: true}),
+			exp:  5 * time.Second,
+		},
+		{
+			name: "zero",
+			err:  testRetryAfterError{delay: 0, ok: true},
+			exp:  0,
+		},
+		{
+			name: "invalid",
+			err:  testRetryAfterError{delay: 5 * time.Second, ok: false},
+			exp:  1 * time.Second,
+		},
+		{
+			name: "negative",
+			err:  testRetryAfterError{delay: -5 * time.Second, ok: true},
+			exp:  1 * time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithRetryAfter(NewConstant(1 * time.Second))
+			if delay, _ := b.Next(tc.err); delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+
+	t.Run("stops", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryAfter(WithMaxRetries(0, NewConstant(1*time.Second)))
+		if delay, _ := b.Next(testRetryAfterError{delay: 5 * time.Second, ok: true}); !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	})
+}
+
+type testStatusError struct {
+	code int
+}
+
+func (e *testStatusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+func TestWithRetryIf(t *testing.T) {
+	t.Parallel()
+
+	// only retry service unavailable
+	pred := func(err error) bool {
+		var serr *testStatusError
+		return errors.As(err, &serr) && serr.code == http.StatusServiceUnavailable
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		stop bool
+	}{
+		{
+			name: "match",
+			err:  &testStatusError{code: http.StatusServiceUnavailable},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("request: %w", &testStatusError{code: http.StatusServiceUnavailable}),
+		},
+		{
+			name: "other_status",
+			err:  &testStatusError{code: http.StatusBadRequest},
+			stop: true,
+		},
+		{
+			name: "other_error",
+			err:  io.EOF,
+			stop: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithRetryIf(pred, NewConstant(1*time.Second))
+			delay, err := b.Next(tc.err)
+			if IsStopped(delay) != tc.stop {
+				t.Errorf("expected stop %v for %v", tc.stop, delay)
+			}
+			if err != tc.err {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestWithRetryableErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		stop bool
+	}{
+		{
+			name: "match",
+			err:  io.EOF,
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("read: %w", io.ErrUnexpectedEOF),
+		},
+		{
+			name: "other",
+			err:  io.ErrClosedPipe,
+			stop: true,
+		},
+		{
+			name: "nil",
+			err:  nil,
+			stop: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithRetryableErrors(NewConstant(1*time.Second), io.EOF, io.ErrUnexpectedEOF)
+			if delay, _ := b.Next(tc.err); IsStopped(delay) != tc.stop {
+				t.Errorf("expected stop %v for %v", tc.stop, delay)
+			}
+		})
+	}
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryableErrors(WithMaxRetries(5, NewConstant(1*time.Nanosecond)), io.EOF)
+
+		var attempts int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return io.EOF
+			}
+			return io.ErrClosedPipe
+		})
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("expected %v to be %v", err, io.ErrClosedPipe)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+}
+
+func TestWithRetryableTimeout(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		stop bool
+	}{
+		{
+			name: "net_timeout",
+			err:  &net.DNSError{Err: "timeout", IsTimeout: true},
+		},
+		{
+			name: "wrapped_net_timeout",
+			err:  fmt.Errorf("lookup: %w", &net.DNSError{Err: "timeout", IsTimeout: true}),
+		},
+		{
+			name: "deadline_exceeded",
+			err:  fmt.Errorf("query: %w", context.DeadlineExceeded),
+		},
+		{
+			name: "net_no_timeout",
+			err:  &net.DNSError{Err: "no such host", IsNotFound: true},
+			stop: true,
+		},
+		{
+			name: "other",
+			err:  io.EOF,
+			stop: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithRetryableTimeout(NewConstant(1 * time.Second))
+			delay, err := b.Next(tc.err)
+			if IsStopped(delay) != tc.stop {
+				t.Errorf("expected stop to be %v, got delay %v", tc.stop, delay)
+			}
+			if err != tc.err {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+		})
+	}
+}
+
+type httpRetryableError struct {
+	err  error
+	resp http.Response
+}
+
+func (e *httpRetryableError) Unwrap() error {
+	return e.err
+}
+
+func (e *httpRetryableError) Error() string {
+	return e.err.Error()
+}
 
 func TestWithCustom(t *testing.T) {
 	WithHTTPResponse := func(next Backoff) Backoff {
@@ -280,11 +2301,11 @@ func TestWithCustom(t *testing.T) {
 
 			switch herr.resp.StatusCode {
 			case 427:
-				retryAfter, err := strconv.Atoi(herr.resp.Header.Get("Retry-After"))
-				if err != nil {
-					retryAfter = 10
+				retryAfter, ok := ParseRetryAfter(herr.resp.Header.Get("Retry-After"), time.Now())
+				if !ok {
+					retryAfter = 10 * time.Second
 				}
-				delay = time.Duration(retryAfter) * time.Second
+				delay = retryAfter
 			case 500:
 				delay = 2 * time.Second
 			}
@@ -344,3 +2365,229 @@ func TestWithCustom(t *testing.T) {
 		t.Errorf("expected non empty body")
 	}
 }
+
+func TestMiddlewareReset(t *testing.T) {
+	t.Parallel()
+
+	// run runs the backoff until it stops, up to a limit, and returns the
+	// delays
+	run := func(b Backoff) []time.Duration {
+		var delays []time.Duration
+		for i := 0; i < 10; i++ {
+			delay, _ := b.Next(io.EOF)
+			if IsStopped(delay) {
+				break
+			}
+			delays = append(delays, delay)
+		}
+		return delays
+	}
+
+	cases := []struct {
+		name    string
+		backoff Backoff
+		exp     []time.Duration
+	}{
+		{
+			name:    "max_retries",
+			backoff: WithMaxRetries(3, NewExponential(1*time.Second)),
+			exp:     []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:    "selective_max_retries",
+			backoff: WithSelectiveMaxRetries(2, func(error) bool { return true }, NewFibonacci(1*time.Second)),
+			exp:     []time.Duration{1 * time.Second, 2 * time.Second},
+		},
+		{
+			name:    "max_retries_context_override",
+			backoff: WithMaxRetriesContextOverride(2, NewConstant(1*time.Second)),
+			exp:     []time.Duration{1 * time.Second, 1 * time.Second},
+		},
+		{
+			name:    "attempt_overrides",
+			backoff: WithMaxRetries(3, WithAttemptOverrides(map[uint64]time.Duration{1: 5 * time.Second}, NewConstant(1*time.Second))),
+			exp:     []time.Duration{5 * time.Second, 1 * time.Second, 1 * time.Second},
+		},
+		{
+			name:    "exponential_floor",
+			backoff: WithMaxRetries(3, WithExponentialFloor(2*time.Second, NewExponential(1*time.Second))),
+			exp:     []time.Duration{2 * time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:    "burst",
+			backoff: WithMaxRetries(3, NewBurst(1, NewExponential(1*time.Second))),
+			exp:     []time.Duration{0, 1 * time.Second, 2 * time.Second},
+		},
+		{
+			name:    "stateless_chain",
+			backoff: WithMaxRetries(3, WithCappedDuration(3*time.Second, NewExponential(1*time.Second))),
+			exp:     []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for i := 0; i < 2; i++ {
+				if got := run(tc.backoff); !reflect.DeepEqual(got, tc.exp) {
+					t.Errorf("run %d: expected %v to be %v", i+1, got, tc.exp)
+				}
+				tc.backoff.(Resettable).Reset()
+			}
+		})
+	}
+
+	t.Run("max_duration", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxDuration(20*time.Millisecond, NewConstant(1*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		if delay, _ := b.Next(nil); !IsStopped(delay) {
+			t.Fatalf("expected %v to stop", delay)
+		}
+
+		b.(Resettable).Reset()
+		if delay, _ := b.Next(nil); delay != 1*time.Millisecond {
+			t.Errorf("expected %v to be %v", delay, 1*time.Millisecond)
+		}
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	const goroutines = 100
+
+	next := func() Backoff {
+		return NewExponential(1 * time.Nanosecond)
+	}
+	always := func(error) bool { return true }
+
+	cases := []struct {
+		name    string
+		backoff Backoff
+	}{
+		{name: "max_duration", backoff: WithMaxDuration(1*time.Hour, next())},
+		{name: "max_retries", backoff: WithMaxRetries(50, next())},
+		{name: "max_attempts", backoff: WithMaxAttempts(50, next())},
+		{name: "max_consecutive_retries", backoff: WithMaxConsecutiveRetries(50, next())},
+		{name: "selective_max_retries", backoff: WithSelectiveMaxRetries(50, always, next())},
+		{name: "max_retries_context_override", backoff: WithMaxRetriesContextOverride(50, next())},
+		{name: "attempt_overrides", backoff: WithAttemptOverrides(map[uint64]time.Duration{2: 1}, next())},
+		{name: "reset_on_error_change", backoff: WithResetOnErrorChange(next())},
+		{name: "deadline_reset_detector", backoff: WithDeadlineResetDetector(next())},
+		{name: "reset_after", backoff: WithResetAfter(1*time.Nanosecond, next())},
+		{name: "exponential_floor", backoff: WithExponentialFloor(1*time.Millisecond, next())},
+		{name: "decaying_jitter", backoff: WithDecayingJitter(0.5, 0.5, next())},
+		{name: "jitter_source", backoff: WithJitterSource(1*time.Millisecond, rand.New(rand.NewSource(1)), next())},
+		{name: "sampled_give_up", backoff: WithSampledGiveUp(0.5, func(error) {}, WithMaxRetries(10, next()))},
+		{name: "warmup", backoff: WithWarmup(1*time.Millisecond, next(), next())},
+		{name: "max_duration_timer", backoff: NewMaxDurationTimer(1*time.Hour, next())},
+		{name: "retry_budget", backoff: WithRetryBudget(1000, 50, next())},
+		{name: "error_rate_breaker", backoff: WithErrorRateBreaker(1*time.Second, 0.5, next())},
+		{name: "burst", backoff: NewBurst(10, next())},
+		{name: "decorrelated_jitter", backoff: NewDecorrelatedJitter(1*time.Nanosecond, 1*time.Second)},
+		{name: "exponential_base", backoff: NewExponentialBase(1*time.Nanosecond, 1.5)},
+		{name: "latency_adaptive", backoff: NewLatencyAdaptive(1*time.Nanosecond, 1*time.Second, 0.5)},
+		{name: "grpc", backoff: NewGRPCBackoff(1*time.Nanosecond, 1*time.Second, 1.6, 1)},
+		{name: "grpc_connection", backoff: NewGRPCConnectionBackoff(1*time.Nanosecond, 1*time.Second, 1.6, 0.2)},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 10; j++ {
+						tc.backoff.Next(io.EOF)
+						Peek(tc.backoff, io.EOF)
+						if j == 5 {
+							tc.backoff.(Resettable).Reset()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestSetDeterministic(t *testing.T) {
+	SetDeterministic(true)
+	defer SetDeterministic(false)
+
+	next := NewConstant(1 * time.Second)
+	cases := []struct {
+		name    string
+		backoff Backoff
+		exp     time.Duration
+	}{
+		{
+			name:    "jitter",
+			backoff: WithJitter(500*time.Millisecond, false, next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "jitter_add_only",
+			backoff: WithJitter(500*time.Millisecond, true, next),
+			exp:     1250 * time.Millisecond,
+		},
+		{
+			name:    "jitter_percent",
+			backoff: WithJitterPercent(10, false, next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "jitter_percent_add_only",
+			backoff: WithJitterPercent(10, true, next),
+			exp:     1050 * time.Millisecond,
+		},
+		{
+			name:    "decaying_jitter",
+			backoff: WithDecayingJitter(0.5, 0.9, next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "jitter_cap",
+			backoff: WithJitterCap(100*time.Millisecond, 500*time.Millisecond, next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "jitter_source",
+			backoff: WithJitterSource(500*time.Millisecond, rand.New(rand.NewSource(1)), next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "jitter_percent_source",
+			backoff: WithJitterPercentSource(10, rand.New(rand.NewSource(1)), next),
+			exp:     1 * time.Second,
+		},
+		{
+			name:    "additive_random",
+			backoff: WithAdditiveRandom(500*time.Millisecond, next),
+			exp:     1250 * time.Millisecond,
+		},
+		{
+			name:    "soft_cap",
+			backoff: WithSoftCap(500*time.Millisecond, 100*time.Millisecond, next),
+			exp:     500 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range cases {
+		for i := 0; i < 100; i++ {
+			if delay, _ := tc.backoff.Next(nil); delay != tc.exp {
+				t.Fatalf("%s: expected %v to be %v", tc.name, delay, tc.exp)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DoBatch retries a batch of items with two tiers of pacing, as common with
+// bulk APIs. The batch is processed in rounds. Within a round, f is called for
+// each pending item and retried using a fresh backoff created by itemBackoff.
+// Items that still fail once their backoff stops are carried over to the next
+// round. Between two rounds, the cooldown is determined by roundBackoff, which
+// typically waits longer than the backoff of the items.
+//
+// The items are processed sequentially in their given order. Once all items
+// succeeded, nil is returned. If roundBackoff stops or the context is
+// canceled, the errors of the pending items are returned joined (see
+// errors.Join), each annotated with the index of its item, along with the
+// error returned by Do (e.g. the error of the context). The round backoff
+// receives these joined errors as well, so that it is able to decide based on
+// them (e.g. using WithRetryable, if any of them is marked as retryable).
+func DoBatch[T any](ctx context.Context, itemBackoff Factory, roundBackoff Backoff, items []T, f func(ctx context.Context, item T) error) error {
+	pending := make([]int, len(items))
+	for i := range pending {
+		pending[i] = i
+	}
+	errs := make([]error, len(items))
+	var roundErr error
+
+	err := Do(ctx, roundBackoff, func(ctx context.Context) error {
+		var failed []int
+		var roundErrs []error
+		for _, i := range pending {
+			if err := Do(ctx, itemBackoff(), func(ctx context.Context) error {
+				return f(ctx, items[i])
+			}); err != nil {
+				errs[i] = fmt.Errorf("item %d: %w", i, err)
+				failed = append(failed, i)
+				roundErrs = append(roundErrs, errs[i])
+			}
+		}
+
+		pending = failed
+		roundErr = errors.Join(roundErrs...)
+		return roundErr
+	})
+	if err == nil {
+		return nil
+	}
+
+	// the error returned by Do usually wraps the errors of the last round,
+	// which are the ones of the pending items
+	if roundErr != nil && errors.Is(err, roundErr) {
+		return err
+	}
+	pendingErrs := make([]error, 0, len(pending)+1)
+	for _, i := range pending {
+		pendingErrs = append(pendingErrs, errs[i])
+	}
+	return errors.Join(append(pendingErrs, err)...)
+}
+
+// DoAll retries independent operations concurrently under a shared time
+// budget. Each function is retried using a fresh backoff created by factory,
+// while the budget bounds all of them together, e.g. to meet a single SLA for
+// a group of requests. DoAll returns once all functions succeeded, gave up or
+// the budget is exhausted.
+//
+// The errors of the failed functions are returned joined (see errors.Join),
+// each annotated with the index of its function. If a function was still
+// retrying when the budget was exhausted, its error includes the error of its
+// last attempt along with context.DeadlineExceeded (see
+// PreferLastErrorOnCancel).
+func DoAll(ctx context.Context, factory Factory, budget time.Duration, fns ...RetryFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, f := range fns {
+		wg.Add(1)
+		go func(i int, f RetryFunc) {
+			defer wg.Done()
+			if err := Do(ctx, factory(), f, PreferLastErrorOnCancel(true)); err != nil {
+				errs[i] = fmt.Errorf("operation %d: %w", i, err)
+			}
+		}(i, f)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,189 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDoBatch(t *testing.T) {
+	t.Parallel()
+
+	itemBackoff := func() Backoff {
+		return WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		// item i succeeds on its (i+1)-th call
+		calls := make(map[int]int)
+		var rounds int
+		err := DoBatch(ctx, itemBackoff, BackoffFunc(func(err error) (time.Duration, error) {
+			rounds++
+			return b.Next(err)
+		}), []int{0, 1, 2, 3}, func(_ context.Context, item int) error {
+			calls[item]++
+			if calls[item] <= item {
+				return io.EOF
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		// 2 calls per round and item, so item 3 requires a second round
+		if got, want := rounds, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := fmt.Sprint(calls), "map[0:1 1:2 2:3 3:4]"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		calls := make(map[string]int)
+		err := DoBatch(ctx, itemBackoff, b, []string{"a", "b", "c"}, func(_ context.Context, item string) error {
+			calls[item]++
+			if item == "b" {
+				return io.EOF
+			}
+			return nil
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if got, want := err.Error(), "item 1: EOF"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+
+		// 3 rounds with 2 calls each
+		if got, want := fmt.Sprint(calls), "map[a:1 b:6 c:1]"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		b := NewConstant(1 * time.Hour)
+
+		err := DoBatch(ctx, itemBackoff, b, []int{1, 2}, func(_ context.Context, item int) error {
+			return io.EOF
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func ExampleDoBatch() {
+	ctx := context.Background()
+
+	// retry each item a few times quickly ...
+	itemBackoff := func() Backoff {
+		return WithMaxRetries(3, NewExponential(50*time.Millisecond))
+	}
+	// ... but wait longer between the rounds of the whole batch
+	roundBackoff := WithMaxRetries(5, NewExponential(5*time.Second))
+
+	records := []string{"a", "b", "c"}
+	if err := DoBatch(ctx, itemBackoff, roundBackoff, records, func(_ context.Context, record string) error {
+		// TODO: submit the record
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
+
+func TestDoAll(t *testing.T) {
+	t.Parallel()
+
+	factory := func() Backoff {
+		return NewConstant(10 * time.Millisecond)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make([]int, 3)
+		fns := make([]RetryFunc, len(calls))
+		for i := range fns {
+			i := i
+			fns[i] = func(_ context.Context) error {
+				calls[i]++
+				if calls[i] <= i {
+					return io.EOF
+				}
+				return nil
+			}
+		}
+
+		if err := DoAll(context.Background(), factory, 5*time.Second, fns...); err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := fmt.Sprint(calls), "[1 2 3]"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		t.Parallel()
+
+		errFoo := errors.New("foo")
+		start := time.Now()
+		err := DoAll(context.Background(), factory, 50*time.Millisecond, func(_ context.Context) error {
+			return nil
+		}, func(_ context.Context) error {
+			return errFoo
+		})
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected %v to be less than 5s", elapsed)
+		}
+		if !errors.Is(err, errFoo) {
+			t.Errorf("expected %v to be %v", err, errFoo)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := err.Error(), "operation 1: context deadline exceeded\nfoo"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+}
+
+func ExampleDoAll() {
+	ctx := context.Background()
+
+	factory := func() Backoff {
+		return WithMaxRetries(5, NewExponential(100*time.Millisecond))
+	}
+
+	// retry both requests, but give up on them after 10s in total
+	if err := DoAll(ctx, factory, 10*time.Second, func(_ context.Context) error {
+		// TODO: first request
+		return nil
+	}, func(_ context.Context) error {
+		// TODO: second request
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
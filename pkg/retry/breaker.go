@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errorRateMinSamples is the minimum number of outcomes within the window
+// before WithErrorRateBreaker opens, so that a few early failures do not open
+// it.
+const errorRateMinSamples = 10
+
+// breakerState is the state of a circuit breaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// outcome is an outcome observed by a circuit breaker.
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+type errorRateBreaker struct {
+	*middleware
+
+	window    time.Duration
+	threshold float64
+
+	l        sync.Mutex
+	state    breakerState
+	openedAt time.Time
+	outcomes []outcome
+}
+
+// WithErrorRateBreaker wraps a backoff with a circuit breaker, that opens when
+// the rate of failed attempts within a sliding window exceeds the threshold
+// (e.g. 0.5 for 50%). While open, the backoff stops immediately. After a
+// cooldown of one window, the breaker half-opens and lets attempts through
+// again: the next success closes it, the next failure opens it again. The
+// breaker only opens once at least 10 outcomes were observed within the
+// window.
+//
+// The breaker is fed by the success and failure signals of the retry loop (see
+// Observer), and is meant to be shared by all calls to a dependency, so that
+// it sees their combined error rate. It panics if the window is not greater
+// than 0 or the threshold is not within [0, 1].
+func WithErrorRateBreaker(window time.Duration, threshold float64, next Backoff) Backoff {
+	if window <= 0 {
+		panic("window must be greater than 0")
+	}
+	if threshold < 0 || threshold > 1 {
+		panic("threshold must be between 0 and 1")
+	}
+
+	b := &errorRateBreaker{
+		window:    window,
+		threshold: threshold,
+	}
+	b.middleware = wrap("WithErrorRateBreaker", fmt.Sprintf("%v, %v", window, threshold), next, func(ctx context.Context, err error) (time.Duration, error) {
+		if !b.allow(isPeek(ctx)) {
+			recordStop(ctx, StopCircuitOpen)
+			return Stop, err
+		}
+		return nextContext(ctx, next, err)
+	})
+	return b
+}
+
+// allow reports whether the breaker lets the next attempt through. If peek is
+// true, the state is not changed.
+func (b *errorRateBreaker) allow(peek bool) bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	now := timeNow()
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.window {
+			return false
+		}
+		if !peek {
+			b.state = breakerHalfOpen
+		}
+		return true
+	case breakerHalfOpen:
+		return true
+	}
+
+	b.prune(now)
+	if len(b.outcomes) < errorRateMinSamples {
+		return true
+	}
+	var failures int
+	for _, o := range b.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(b.outcomes)) <= b.threshold {
+		return true
+	}
+	if !peek {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+	return false
+}
+
+// prune drops the outcomes that left the window.
+func (b *errorRateBreaker) prune(now time.Time) {
+	var i int
+	for i < len(b.outcomes) && now.Sub(b.outcomes[i].at) >= b.window {
+		i++
+	}
+	b.outcomes = append(b.outcomes[:0], b.outcomes[i:]...)
+}
+
+// observe records an outcome.
+func (b *errorRateBreaker) observe(failed bool) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	now := timeNow()
+	switch b.state {
+	case breakerOpen:
+		return
+	case breakerHalfOpen:
+		b.outcomes = b.outcomes[:0]
+		if failed {
+			b.state = breakerOpen
+			b.openedAt = now
+			return
+		}
+		b.state = breakerClosed
+	}
+
+	b.prune(now)
+	b.outcomes = append(b.outcomes, outcome{at: now, failed: failed})
+}
+
+// ObserveSuccess implements Observer.
+func (b *errorRateBreaker) ObserveSuccess() {
+	b.observe(false)
+}
+
+// ObserveFailure implements Observer.
+func (b *errorRateBreaker) ObserveFailure(_ error) {
+	b.observe(true)
+}
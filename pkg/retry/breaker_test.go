@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithErrorRateBreaker(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo")
+
+	t.Run("min_samples", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithErrorRateBreaker(1*time.Minute, 0.5, NewConstant(1*time.Second))
+		o := b.(Observer)
+		for i := 0; i < errorRateMinSamples-1; i++ {
+			o.ObserveFailure(errFoo)
+			if delay, _ := b.Next(errFoo); IsStopped(delay) {
+				t.Fatalf("attempt %d: should not stop", i+1)
+			}
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithErrorRateBreaker(1*time.Minute, 0.5, NewConstant(1*time.Second))
+		o := b.(Observer)
+		for i := 0; i < 5; i++ {
+			o.ObserveSuccess()
+			o.ObserveFailure(errFoo)
+		}
+		if delay, _ := b.Next(errFoo); IsStopped(delay) {
+			t.Fatal("should not stop at the threshold")
+		}
+
+		o.ObserveFailure(errFoo)
+		if delay, ok := Peek(b, errFoo); !ok || !IsStopped(delay) {
+			t.Error("expected peek to stop")
+		}
+		if delay, _ := b.Next(errFoo); !IsStopped(delay) {
+			t.Fatal("should stop above the threshold")
+		}
+
+		// stays open, even after successes
+		o.ObserveSuccess()
+		if delay, _ := b.Next(errFoo); !IsStopped(delay) {
+			t.Error("should stay open")
+		}
+	})
+
+	t.Run("half_open", func(t *testing.T) {
+		t.Parallel()
+
+		window := 50 * time.Millisecond
+		b := WithErrorRateBreaker(window, 0, NewConstant(1*time.Second))
+		o := b.(Observer)
+		for i := 0; i < errorRateMinSamples; i++ {
+			o.ObserveFailure(errFoo)
+		}
+		if delay, _ := b.Next(errFoo); !IsStopped(delay) {
+			t.Fatal("should stop")
+		}
+
+		// a failure while half-open opens the breaker again
+		time.Sleep(window)
+		if delay, _ := b.Next(errFoo); IsStopped(delay) {
+			t.Fatal("should half-open")
+		}
+		o.ObserveFailure(errFoo)
+		if delay, _ := b.Next(errFoo); !IsStopped(delay) {
+			t.Fatal("should open again")
+		}
+
+		// a success while half-open closes the breaker
+		time.Sleep(window)
+		if delay, _ := b.Next(errFoo); IsStopped(delay) {
+			t.Fatal("should half-open")
+		}
+		o.ObserveSuccess()
+		for i := 0; i < errorRateMinSamples-2; i++ {
+			o.ObserveFailure(errFoo)
+		}
+		if delay, _ := b.Next(errFoo); IsStopped(delay) {
+			t.Error("should be closed")
+		}
+	})
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithErrorRateBreaker(1*time.Minute, 0.5, NewConstant(1*time.Nanosecond))
+
+		var calls int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			calls++
+			return errFoo
+		})
+		if !errors.Is(err, errFoo) {
+			t.Errorf("expected %v to be %v", err, errFoo)
+		}
+		if got, want := calls, errorRateMinSamples; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func ExampleWithErrorRateBreaker() {
+	ctx := context.Background()
+
+	// shared by all calls to the dependency
+	breaker := WithErrorRateBreaker(30*time.Second, 0.5, NewConstant(100*time.Millisecond))
+
+	b := WithMaxRetries(3, breaker)
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
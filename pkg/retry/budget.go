@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetryBudget is a token bucket limiting the rate of retries. Each retry takes
+// a token, and tokens are refilled at a constant rate up to a maximum burst.
+// While the bucket is empty, retries are refused. Sharing a single budget
+// among all callers of a service (see WithSharedRetryBudget) caps the load
+// added by retries during an outage, no matter how many operations fail
+// concurrently. It is safe for concurrent use.
+type RetryBudget struct {
+	l      sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget creates a retry budget, that is refilled with ratePerSec
+// tokens per second, up to burst tokens. The budget starts full. A rate of 0
+// disables the refill, i.e. the budget allows a total of burst retries.
+//
+// It panics if ratePerSec is negative or burst is less than 1.
+func NewRetryBudget(ratePerSec float64, burst int) *RetryBudget {
+	if !(ratePerSec >= 0) {
+		panic("ratePerSec must not be negative")
+	}
+	if burst < 1 {
+		panic("burst must be at least 1")
+	}
+
+	return &RetryBudget{
+		rate:   ratePerSec,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   timeNow(),
+	}
+}
+
+// refill adds the tokens accrued since the last refill and returns them
+// without storing them, unless store is true. The lock must be held.
+func (b *RetryBudget) refill(now time.Time, store bool) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*b.rate
+	if tokens > b.burst {
+		tokens = b.burst
+	}
+	if store {
+		b.tokens = tokens
+		b.last = now
+	}
+	return tokens
+}
+
+// Tokens returns the number of tokens currently available.
+func (b *RetryBudget) Tokens() float64 {
+	b.l.Lock()
+	defer b.l.Unlock()
+	return b.refill(timeNow(), false)
+}
+
+// allow takes a token, if available, and reports whether it did. If peek is
+// true, no token is taken.
+func (b *RetryBudget) allow(peek bool) bool {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	tokens := b.refill(timeNow(), !peek)
+	if tokens < 1 {
+		return false
+	}
+	if !peek {
+		b.tokens--
+	}
+	return true
+}
+
+// WithRetryBudget limits the rate of retries using a token bucket, that is
+// refilled with ratePerSec tokens per second, up to burst tokens (see
+// NewRetryBudget). Each retry takes a token; while none is available, the
+// backoff stops. The budget is shared by all users of the returned backoff.
+// Use WithSharedRetryBudget to share a budget among multiple backoffs.
+//
+// It panics if ratePerSec is negative or burst is less than 1.
+func WithRetryBudget(ratePerSec float64, burst int, next Backoff) Backoff {
+	return WithSharedRetryBudget(NewRetryBudget(ratePerSec, burst), next)
+}
+
+// WithSharedRetryBudget is like WithRetryBudget, but takes the tokens from the
+// given budget. This allows to share a budget among backoffs created per
+// operation, e.g. by a Factory, so that the limit applies to all of them.
+func WithSharedRetryBudget(budget *RetryBudget, next Backoff) Backoff {
+	m := wrap("WithRetryBudget", fmt.Sprintf("%v, %v", budget.rate, budget.burst), next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if IsStopped(delay) {
+			return Stop, err
+		}
+
+		if !budget.allow(isPeek(ctx)) {
+			recordStop(ctx, StopRetryBudget)
+			return Stop, err
+		}
+		return delay, err
+	})
+	m.bound = func(b bounds) bounds {
+		// refilled tokens allow an unknown number of further retries
+		if budget.rate == 0 && (!b.retriesOK || uint64(budget.burst) < b.retries) {
+			b.retries = uint64(budget.burst)
+			b.retriesOK = true
+		}
+		return b
+	}
+	return m
+}
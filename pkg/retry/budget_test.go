@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("drain_and_refill", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryBudget(20, 2, NewConstant(1*time.Second))
+
+		// drain the bucket
+		for i := 0; i < 2; i++ {
+			if delay, _ := b.Next(nil); delay != 1*time.Second {
+				t.Fatalf("retry %d: expected %v to be %v", i+1, delay, 1*time.Second)
+			}
+		}
+		if delay, _ := b.Next(nil); !IsStopped(delay) {
+			t.Fatalf("expected %v to stop", delay)
+		}
+
+		// refill a token after 50ms
+		time.Sleep(60 * time.Millisecond)
+		if delay, _ := b.Next(nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+	})
+
+	t.Run("no_refill", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryBudget(0, 1, NewConstant(1*time.Second))
+		if delay, _ := b.Next(nil); IsStopped(delay) {
+			t.Fatalf("expected %v not to stop", delay)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if delay, _ := b.Next(nil); !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	})
+
+	t.Run("peek", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryBudget(0, 1, NewConstant(1*time.Second))
+		for i := 0; i < 2; i++ {
+			if delay, ok := Peek(b, nil); !ok || delay != 1*time.Second {
+				t.Fatalf("expected %v to be %v", delay, 1*time.Second)
+			}
+		}
+		if delay, _ := b.Next(nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+	})
+
+	t.Run("inner_stop_keeps_tokens", func(t *testing.T) {
+		t.Parallel()
+
+		budget := NewRetryBudget(0, 1)
+		b := WithSharedRetryBudget(budget, WithMaxRetries(0, NewConstant(1*time.Second)))
+		if delay, _ := b.Next(nil); !IsStopped(delay) {
+			t.Fatalf("expected %v to stop", delay)
+		}
+		if tokens := budget.Tokens(); tokens != 1 {
+			t.Errorf("expected %v to be %v", tokens, 1)
+		}
+	})
+}
+
+func TestWithSharedRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	budget := NewRetryBudget(0, 10)
+	factory := func() Backoff {
+		return WithSharedRetryBudget(budget, NewConstant(1*time.Nanosecond))
+	}
+
+	var l sync.Mutex
+	var attempts int
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Do(context.Background(), factory(), func(_ context.Context) error {
+				l.Lock()
+				attempts++
+				l.Unlock()
+				return io.EOF
+			})
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("expected %v to be %v", err, io.EOF)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// each operation makes one attempt, plus a total of 10 retries
+	if attempts != 15 {
+		t.Errorf("expected 15 attempts, got %d", attempts)
+	}
+}
+
+func TestNewRetryBudgetPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{name: "rate", rate: -1, burst: 1},
+		{name: "burst", rate: 1, burst: 0},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("expected panic")
+				}
+			}()
+			NewRetryBudget(tc.rate, tc.burst)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time used by Do, its variants and the time-based
+// middleware (e.g. WithMaxDuration, NewMaxDurationTimer, WithResetAfter or
+// WithRetryBudget). It allows tests to replace the real time by a fake one (see
+// SetClock and the retrytest package). Deadlines of contexts are not affected,
+// since they are based on the real time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep waits for d to elapse. It returns the error of ctx, if ctx is done
+	// before.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the Clock using the real time.
+type realClock struct{}
+
+// Now implements Clock.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep implements Clock.
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleepTimer(ctx, d, nil)
+}
+
+// clockBox holds a Clock, so that it can be stored in an atomic.Pointer.
+type clockBox struct {
+	Clock
+}
+
+// clock holds the Clock set by SetClock.
+var clock atomic.Pointer[clockBox]
+
+// SetClock replaces the source of time used by Do and the time-based
+// middleware. A nil clock restores the real time. It is intended for tests
+// only. The clock is process-global; middleware that measures time from its
+// construction (e.g. WithMaxDuration) must be created after the clock is set.
+//
+// With the real clock, Do waits using a time.Timer, which also makes it
+// compatible with the virtual time of testing/synctest.
+func SetClock(c Clock) {
+	if c == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&clockBox{c})
+}
+
+// currentClock returns the clock set by SetClock or the real clock.
+func currentClock() Clock {
+	if c := clock.Load(); c != nil {
+		return c.Clock
+	}
+	return realClock{}
+}
+
+// timeNow returns the current time of the current clock.
+func timeNow() time.Time {
+	return currentClock().Now()
+}
+
+// timeSince returns the time elapsed since t according to the current clock.
+func timeSince(t time.Time) time.Duration {
+	return timeNow().Sub(t)
+}
+
+// sleep waits for d using the current clock. It returns early without error
+// when wake is closed and with the error of ctx when ctx is done.
+func sleep(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	c := currentClock()
+	if _, ok := c.(realClock); ok {
+		return sleepTimer(ctx, d, wake)
+	}
+
+	sctx := ctx
+	if wake != nil {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-wake:
+				cancel()
+			case <-sctx.Done():
+			}
+		}()
+	}
+	if err := c.Sleep(sctx, d); err != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// sleepTimer waits for d using a time.Timer. It returns early without error
+// when wake is closed and with the error of ctx when ctx is done.
+func sleepTimer(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wake:
+		return nil
+	case <-t.C:
+		return nil
+	}
+}
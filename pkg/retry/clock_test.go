@@ -0,0 +1,169 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry/retrytest"
+)
+
+func TestSetClock(t *testing.T) {
+	c := retrytest.NewFakeClock(time.Now())
+	SetClock(c)
+	defer SetClock(nil)
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- Do(ctx, NewConstant(1*time.Hour), func(_ context.Context) error {
+				return RetryableError(errors.New("oops"))
+			})
+		}()
+		if err := c.BlockUntil(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		b := WithMaxRetries(2, NewConstant(1*time.Minute))
+
+		done := make(chan Summary, 1)
+		go func() {
+			s, _ := DoSummary(context.Background(), b, func(_ context.Context) error {
+				c.Advance(1 * time.Second) // the attempt takes 1s
+				return RetryableError(errors.New("oops"))
+			})
+			done <- s
+		}()
+		for i := 0; i < 2; i++ {
+			if err := c.BlockUntil(context.Background(), 1); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(1 * time.Minute)
+		}
+
+		s := <-done
+		if got, want := s.TotalSleep, 2*time.Minute; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := s.Elapsed, 2*time.Minute+3*time.Second; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("work_budget", func(t *testing.T) {
+		var calls int
+		// the work time is measured by the clock, so the budget is exhausted
+		// after the first attempt
+		err := DoWorkBudget(context.Background(), NewConstant(1*time.Nanosecond), 1*time.Second, func(_ context.Context) error {
+			calls++
+			c.Advance(1 * time.Second)
+			return RetryableError(errors.New("oops"))
+		})
+		if err == nil {
+			t.Error("expected err")
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("budget_split", func(t *testing.T) {
+		// the attempts are retried right away
+		b := BackoffFunc(func(err error) (time.Duration, error) {
+			return 0, err
+		})
+
+		// the remaining budget is measured by the clock: the first attempt
+		// takes 40m of the total of 1h, leaving 20m for the second one
+		var timeouts []time.Duration
+		_ = DoBudgetSplit(context.Background(), b, 1*time.Hour, 2, func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			timeouts = append(timeouts, time.Until(deadline).Round(1*time.Minute))
+			c.Advance(40 * time.Minute)
+			return RetryableError(errors.New("oops"))
+		})
+		if got, want := fmt.Sprint(timeouts), fmt.Sprint([]time.Duration{30 * time.Minute, 20 * time.Minute}); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("attempt_fraction", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), c.Now().Add(1*time.Hour))
+		defer cancel()
+
+		b := WithMaxRetries(1, BackoffFunc(func(err error) (time.Duration, error) {
+			return 0, err
+		}))
+
+		// the remaining time is measured by the clock: after the first attempt
+		// took 40m, the second one gets half of the remaining 20m
+		var timeouts []time.Duration
+		_ = DoAttemptFraction(ctx, b, 0.5, func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			timeouts = append(timeouts, time.Until(deadline).Round(1*time.Minute))
+			c.Advance(40 * time.Minute)
+			return RetryableError(errors.New("oops"))
+		})
+		if got, want := fmt.Sprint(timeouts), fmt.Sprint([]time.Duration{30 * time.Minute, 10 * time.Minute}); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("max_duration_timer", func(t *testing.T) {
+		b := NewMaxDurationTimer(1*time.Minute, NewConstant(1*time.Second))
+		defer b.Stop()
+		if err := c.BlockUntil(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+
+		c.Advance(59 * time.Second)
+		if delay, _ := b.Next(nil); delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+		select {
+		case <-b.Done():
+			t.Fatal("expected Done not to be closed")
+		default:
+		}
+
+		c.Advance(1 * time.Second)
+		<-b.Done()
+		if delay, _ := b.Next(nil); !IsStopped(delay) {
+			t.Errorf("should stop")
+		}
+	})
+
+	t.Run("trigger_now", func(t *testing.T) {
+		var attempts int
+		ctl, errCh := DoControllable(context.Background(), WithMaxRetries(1, NewConstant(1*time.Hour)), func(_ context.Context) error {
+			attempts++
+			return RetryableError(errors.New("oops"))
+		})
+		if err := c.BlockUntil(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		ctl.TriggerNow()
+
+		if err := <-errCh; err == nil {
+			t.Error("expected err")
+		}
+		if attempts != 2 {
+			t.Errorf("expected %v to be %v", attempts, 2)
+		}
+		if got := c.Sleepers(); got != 0 {
+			t.Errorf("expected %v to be 0", got)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// Control controls a retry loop started by DoControllable. It is safe for
+// concurrent use.
+type Control struct {
+	mu      sync.Mutex
+	resumed chan struct{} // nil unless paused; closed on Resume
+	wake    chan struct{} // nil unless sleeping; closed on TriggerNow
+}
+
+// Pause halts the retry loop before its next attempt. An attempt that is
+// already running is not interrupted.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed == nil {
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume continues a paused retry loop.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed != nil {
+		close(c.resumed)
+		c.resumed = nil
+	}
+}
+
+// TriggerNow skips the current wait between two attempts, so that the next
+// attempt is started right away (unless the loop is paused). It has no effect
+// if the loop is not waiting.
+func (c *Control) TriggerNow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.wake != nil {
+		close(c.wake)
+		c.wake = nil
+	}
+}
+
+// waitResumed blocks while the loop is paused. It returns the error of the
+// context, if it is canceled in the meantime.
+func (c *Control) waitResumed(ctx context.Context) error {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
+
+// beginSleep returns a channel that is closed by TriggerNow.
+func (c *Control) beginSleep() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wake = make(chan struct{})
+	return c.wake
+}
+
+// endSleep ends the wait started by beginSleep.
+func (c *Control) endSleep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wake = nil
+}
+
+// DoControllable is like Do, but runs the retry loop in a new goroutine, that
+// can be controlled through the returned Control. This is intended for
+// interactive tooling, such as debuggers. The final error (nil on success) is
+// sent on the returned channel, which is closed afterwards.
+func DoControllable(ctx context.Context, b Backoff, f RetryFunc, opts ...Option) (*Control, <-chan error) {
+	c := newConfig(opts)
+	ctl := &Control{}
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		errCh <- do(ctx, b, f, c, ctl)
+	}()
+	return ctl, errCh
+}
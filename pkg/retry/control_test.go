@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoControllable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("trigger_now", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Hour)
+
+		var attempts int32
+		ctl, errCh := DoControllable(ctx, b, func(_ context.Context) error {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return fmt.Errorf("oops")
+			}
+			return nil
+		})
+
+		for {
+			ctl.TriggerNow()
+			select {
+			case err := <-errCh:
+				if err != nil {
+					t.Fatalf("expected no err, got %v", err)
+				}
+				if got, want := atomic.LoadInt32(&attempts), int32(3); got != want {
+					t.Errorf("expected %v to be %v", got, want)
+				}
+				return
+			case <-time.After(1 * time.Millisecond):
+			}
+		}
+	})
+
+	t.Run("pause_resume", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var attempts int32
+		ctl, errCh := DoControllable(ctx, b, func(_ context.Context) error {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				close(started)
+				<-release
+				return fmt.Errorf("oops")
+			}
+			return nil
+		})
+
+		<-started
+		ctl.Pause()
+		close(release)
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("expected to be paused, got %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if got, want := atomic.LoadInt32(&attempts), int32(1); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+
+		ctl.Resume()
+		if err := <-errCh; err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := atomic.LoadInt32(&attempts), int32(2); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("cancel_while_paused", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		b := NewConstant(1 * time.Nanosecond)
+
+		ctl, errCh := DoControllable(ctx, b, func(_ context.Context) error {
+			return fmt.Errorf("oops")
+		})
+		ctl.Pause()
+		cancel()
+
+		if err := <-errCh; err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+}
+
+func ExampleDoControllable() {
+	ctx := context.Background()
+
+	b := NewExponential(1 * time.Second)
+	b = WithMaxRetries(5, b)
+
+	ctl, errCh := DoControllable(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	})
+
+	// e.g. on user input
+	ctl.TriggerNow()
+
+	if err := <-errCh; err != nil {
+		// handle error
+	}
+}
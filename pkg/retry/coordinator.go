@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Coordinator lets multiple backoffs, that belong to one logical operation,
+// cooperatively stop retrying. Once it is marked as done, all backoffs
+// associated with it via WithCoordinator stop. The zero value is ready to use.
+// It is safe for concurrent use.
+type Coordinator struct {
+	done uint32
+}
+
+// MarkDone marks the coordinated operation as done. All associated backoffs
+// stop on their next call.
+func (c *Coordinator) MarkDone() {
+	atomic.StoreUint32(&c.done, 1)
+}
+
+// IsDone reports whether the coordinated operation is done.
+func (c *Coordinator) IsDone() bool {
+	return atomic.LoadUint32(&c.done) == 1
+}
+
+// WithCoordinator associates the next backoff with the coordinator. As soon as
+// the coordinator is marked as done, the backoff stops. This is useful for
+// fan-in scenarios, where several retried sub-operations can abandon their
+// work once one of them achieved the goal.
+func WithCoordinator(c *Coordinator, next Backoff) Backoff {
+	return wrap("WithCoordinator", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		if c.IsDone() {
+			return Stop, err
+		}
+		return nextContext(ctx, next, err)
+	})
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithCoordinator(t *testing.T) {
+	t.Parallel()
+
+	var c Coordinator
+	b1 := WithCoordinator(&c, NewConstant(1*time.Second))
+	b2 := WithCoordinator(&c, NewConstant(1*time.Second))
+
+	for _, b := range []Backoff{b1, b2} {
+		delay, _ := b.Next(nil)
+		if IsStopped(delay) {
+			t.Errorf("should not stop")
+		}
+	}
+
+	c.MarkDone()
+
+	for _, b := range []Backoff{b1, b2} {
+		delay, _ := b.Next(nil)
+		if !IsStopped(delay) {
+			t.Errorf("should stop")
+		}
+	}
+}
+
+func ExampleWithCoordinator() {
+	ctx := context.Background()
+
+	// Query several mirrors, but stop all retries as soon as one of them
+	// responded.
+	var c Coordinator
+	var wg sync.WaitGroup
+	for _, mirror := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(mirror string) {
+			defer wg.Done()
+
+			b := WithCoordinator(&c, NewExponential(100*time.Millisecond))
+			if err := Do(ctx, b, func(_ context.Context) error {
+				// TODO: query the mirror
+				return fmt.Errorf("mirror %s unavailable", mirror)
+			}); err == nil {
+				c.MarkDone()
+			}
+		}(mirror)
+	}
+
+	// for the sake of the example
+	c.MarkDone()
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRetryAfter parses the value of a Retry-After header of HTTP (see RFC
+// 7231, section 7.1.3) and returns the delay until the indicated time relative
+// to now. The value is either a number of seconds (e.g. "120") or an HTTP-date
+// (e.g. "Wed, 21 Oct 2015 07:28:00 GMT"). A date in the past results in a delay
+// of 0. The returned bool reports whether the value could be parsed.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if isDigits(value) {
+		secs, err := strconv.ParseUint(value, 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, false
+		}
+		// ParseUint returns the maximum value on overflow
+		return mulSat(time.Second, secs), true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := t.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// isDigits reports whether s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		value string
+		exp   time.Duration
+		ok    bool
+	}{
+		{
+			name:  "seconds",
+			value: "120",
+			exp:   120 * time.Second,
+			ok:    true,
+		},
+		{
+			name:  "zero",
+			value: "0",
+			exp:   0,
+			ok:    true,
+		},
+		{
+			name:  "whitespace",
+			value: " 5 ",
+			exp:   5 * time.Second,
+			ok:    true,
+		},
+		{
+			name:  "seconds_overflow",
+			value: "99999999999999999999999",
+			exp:   math.MaxInt64,
+			ok:    true,
+		},
+		{
+			name:  "date",
+			value: "Wed, 21 Oct 2015 07:30:00 GMT",
+			exp:   2 * time.Minute,
+			ok:    true,
+		},
+		{
+			name:  "date_rfc850",
+			value: "Wednesday, 21-Oct-15 07:29:00 GMT",
+			exp:   1 * time.Minute,
+			ok:    true,
+		},
+		{
+			name:  "date_past",
+			value: "Wed, 21 Oct 2015 07:00:00 GMT",
+			exp:   0,
+			ok:    true,
+		},
+		{
+			name:  "empty",
+			value: "",
+		},
+		{
+			name:  "negative",
+			value: "-5",
+		},
+		{
+			name:  "fraction",
+			value: "1.5",
+		},
+		{
+			name:  "malformed",
+			value: "soon",
+		},
+		{
+			name:  "malformed_date",
+			value: "Wed, 32 Oct 2015 07:30:00 GMT",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			delay, ok := ParseRetryAfter(tc.value, now)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %v to be %v", ok, tc.ok)
+			}
+			if delay != tc.exp {
+				t.Errorf("expected %v to be %v", delay, tc.exp)
+			}
+		})
+	}
+}
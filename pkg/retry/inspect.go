@@ -0,0 +1,285 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ValidationError is returned by Validate and lists the suspicious parts of a
+// composed backoff.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns the error string.
+func (e *ValidationError) Error() string {
+	return "suspicious backoff composition: " + strings.Join(e.Problems, "; ")
+}
+
+// chain returns the backoffs of a composed chain, starting with the outermost
+// one. The walk stops at the first backoff that does not implement Wrapper.
+func chain(b Backoff) []Backoff {
+	var bs []Backoff
+	for b != nil {
+		bs = append(bs, b)
+		w, ok := b.(Wrapper)
+		if !ok {
+			break
+		}
+		b = w.Inner()
+	}
+	return bs
+}
+
+// name returns the name of a middleware, including the types embedding one
+// (e.g. MaxDurationTimer), or an empty string for any other backoff.
+func name(b Backoff) string {
+	if m, ok := b.(interface{ base() *middleware }); ok {
+		return m.base().name
+	}
+	return ""
+}
+
+// limitKinds groups the names of the middleware that limit retrying in the
+// same way. Multiple layers of the same kind are redundant.
+var limitKinds = map[string]string{
+	"WithMaxRetries":            "WithMaxRetries",
+	"WithMaxAttempts":           "WithMaxRetries",
+	"WithMaxConsecutiveRetries": "WithMaxConsecutiveRetries",
+	"WithMaxDuration":           "WithMaxDuration",
+	"MaxDurationTimer":          "WithMaxDuration",
+	"WithCappedDuration":        "WithCappedDuration",
+}
+
+func isMaxRetries(name string) bool {
+	return name == "WithMaxRetries" || name == "WithMaxAttempts"
+}
+
+func isMaxDuration(name string) bool {
+	return name == "WithMaxDuration" || name == "MaxDurationTimer"
+}
+
+func isJitter(name string) bool {
+	return name == "WithJitter" || name == "WithJitterPercent" || name == "WithDecayingJitter" || name == "WithJitterCap" ||
+		name == "WithJitterSource" || name == "WithJitterPercentSource"
+}
+
+// Validate inspects a composed backoff and reports suspicious orderings or
+// redundant layers. It is an opt-in linter, meant to be used when backoffs are
+// assembled at runtime (e.g. from configuration). Only backoffs implementing
+// Wrapper can be inspected; the walk stops at the first one that does not.
+//
+// The following is reported:
+//   - jitter wrapping another jitter
+//   - jitter wrapping WithCappedDuration, WithBand or WithMaxDuration, because
+//     the jitter may push the delay beyond the cap, the band or the remaining
+//     duration
+//   - WithMaxRetries or WithMaxAttempts wrapping WithMaxDuration or a
+//     MaxDurationTimer, because a retry is counted even if the maximum
+//     duration stops retrying; wrap the retry limit by the duration instead
+//   - multiple layers limiting the retries (WithMaxRetries or
+//     WithMaxAttempts), the duration (WithMaxDuration or a MaxDurationTimer)
+//     or the delay (WithCappedDuration)
+//
+// It returns a *ValidationError if any problem was found and nil otherwise.
+func Validate(b Backoff) error {
+	bs := chain(b)
+	var problems []string
+
+	seen := make(map[string]bool)
+	for i, outer := range bs {
+		n := name(outer)
+		if n == "" {
+			continue
+		}
+
+		if kind, ok := limitKinds[n]; ok {
+			if seen[kind] {
+				problems = append(problems, "redundant "+n)
+			}
+			seen[kind] = true
+		}
+
+		if isMaxRetries(n) {
+			for _, inner := range bs[i+1:] {
+				if in := name(inner); isMaxDuration(in) {
+					problems = append(problems, n+" wraps "+in+", a retry is counted even if the maximum duration stops")
+				}
+			}
+		}
+
+		if !isJitter(n) {
+			continue
+		}
+		for _, inner := range bs[i+1:] {
+			in := name(inner)
+			switch {
+			case isJitter(in):
+				problems = append(problems, n+" wraps "+in+", jitter is applied twice")
+			case in == "WithCappedDuration":
+				problems = append(problems, n+" wraps "+in+", jitter may exceed the cap")
+			case in == "WithBand":
+				problems = append(problems, n+" wraps "+in+", jitter may leave the band")
+			case in == "WithMaxDuration":
+				problems = append(problems, n+" wraps "+in+", jitter may exceed the maximum duration")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Describe renders the chain of a composed backoff, starting with the
+// outermost one, e.g.:
+//
+//	WithMaxRetries(3) -> WithCappedDuration(10s) -> Exponential(1s)
+//
+// Backoffs implementing fmt.Stringer are rendered using their String method,
+// all others by their type.
+func Describe(b Backoff) string {
+	bs := chain(b)
+	parts := make([]string, 0, len(bs))
+	for _, b := range bs {
+		if s, ok := b.(fmt.Stringer); ok {
+			parts = append(parts, s.String())
+		} else {
+			parts = append(parts, fmt.Sprintf("%T", b))
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// bounds is an estimate of the worst-case behavior of a backoff.
+type bounds struct {
+	// delay returns an upper bound of the delay before the n-th retry
+	// (1-based). It is nil if the delays are unbounded.
+	delay func(n uint64) time.Duration
+
+	// retries is the maximum number of retries, if retriesOK is true.
+	retries   uint64
+	retriesOK bool
+
+	// total is an upper bound of the sum of all delays, if totalOK is true.
+	total   time.Duration
+	totalOK bool
+}
+
+// bounder is implemented by backoffs that are able to estimate their
+// worst-case behavior. The bounds of the wrapped backoff are passed in as
+// inner; generators ignore them.
+type bounder interface {
+	bounds(inner bounds) bounds
+}
+
+// mapDelay applies f to the delay bounds, if any.
+func (b bounds) mapDelay(f func(d time.Duration) time.Duration) bounds {
+	if b.delay != nil {
+		inner := b.delay
+		b.delay = func(n uint64) time.Duration {
+			return f(inner(n))
+		}
+	}
+	return b
+}
+
+// addPerRetry increases the total by d for every retry.
+func (b bounds) addPerRetry(d time.Duration) bounds {
+	if !b.totalOK {
+		return b
+	}
+	if !b.retriesOK {
+		b.totalOK = false
+		return b
+	}
+	b.total = addSat(b.total, mulSat(d, b.retries))
+	return b
+}
+
+// maxSummedRetries limits the number of delays summed up by tighten.
+const maxSummedRetries = 1 << 20
+
+// tighten updates the total with the sum of the bounded delays, if it is
+// smaller.
+func (b bounds) tighten() bounds {
+	if !b.retriesOK || b.delay == nil || b.retries > maxSummedRetries {
+		return b
+	}
+
+	var sum time.Duration
+	for n := uint64(1); n <= b.retries && sum < math.MaxInt64; n++ {
+		sum = addSat(sum, b.delay(n))
+	}
+	if !b.totalOK || sum < b.total {
+		b.total = sum
+		b.totalOK = true
+	}
+	return b
+}
+
+// addSat adds two non-negative durations, saturating at the maximum
+// time.Duration.
+func addSat(a, b time.Duration) time.Duration {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// mulSat multiplies a non-negative duration by n, saturating at the maximum
+// time.Duration.
+func mulSat(d time.Duration, n uint64) time.Duration {
+	if d == 0 || n == 0 {
+		return 0
+	}
+	if n > uint64(math.MaxInt64/d) {
+		return math.MaxInt64
+	}
+	return d * time.Duration(n)
+}
+
+// scaleSat multiplies a non-negative duration by f, saturating at the maximum
+// time.Duration.
+func scaleSat(d time.Duration, f float64) time.Duration {
+	return durationSat(float64(d) * f)
+}
+
+// durationSat converts a non-negative number of nanoseconds to a duration,
+// saturating at the maximum time.Duration.
+func durationSat(ns float64) time.Duration {
+	if ns >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(ns)
+}
+
+// MaxTotalTime estimates the worst-case total time a backoff waits before it
+// gives up, i.e. the sum of all its delays. It is meant for planning, e.g. for
+// setting sane timeouts or reasoning about SLAs. The time spent in the retried
+// function itself is not included.
+//
+// The estimate requires the backoff to be composed of the built-in generators
+// and middleware. It returns false if the backoff is unbounded, or if it
+// contains a backoff whose behavior is unknown (e.g. a BackoffFunc).
+func MaxTotalTime(b Backoff) (time.Duration, bool) {
+	bs := chain(b)
+
+	var bd bounds
+	for i := len(bs) - 1; i >= 0; i-- {
+		bb, ok := bs[i].(bounder)
+		if !ok {
+			bd = bounds{}
+			continue
+		}
+		bd = bb.bounds(bd).tighten()
+	}
+
+	if !bd.totalOK {
+		return 0, false
+	}
+	return bd.total, true
+}
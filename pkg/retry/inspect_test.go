@@ -0,0 +1,362 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		backoff  Backoff
+		problems []string
+	}{
+		{
+			name:    "generator",
+			backoff: NewFibonacci(1 * time.Second),
+		},
+		{
+			name: "valid",
+			backoff: WithMaxRetries(5,
+				WithCappedDuration(10*time.Second,
+					WithJitter(1*time.Second, false,
+						NewExponential(1*time.Second)))),
+		},
+		{
+			name: "double_jitter",
+			backoff: WithJitter(1*time.Second, false,
+				WithJitterPercent(5, false,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"WithJitter wraps WithJitterPercent, jitter is applied twice",
+			},
+		},
+		{
+			name: "jitter_over_cap",
+			backoff: WithJitterPercent(5, false,
+				WithCappedDuration(10*time.Second,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"WithJitterPercent wraps WithCappedDuration, jitter may exceed the cap",
+			},
+		},
+		{
+			name: "jitter_over_band",
+			backoff: WithJitter(1*time.Second, false,
+				WithBand(1*time.Second, 10*time.Second,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"WithJitter wraps WithBand, jitter may leave the band",
+			},
+		},
+		{
+			name: "jitter_over_max_duration",
+			backoff: WithJitter(1*time.Second, true,
+				WithMaxDuration(10*time.Second,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"WithJitter wraps WithMaxDuration, jitter may exceed the maximum duration",
+			},
+		},
+		{
+			name: "redundant",
+			backoff: WithMaxRetries(3,
+				WithMaxRetries(5,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"redundant WithMaxRetries",
+			},
+		},
+		{
+			name: "redundant_max_attempts",
+			backoff: WithMaxRetries(3,
+				WithMaxAttempts(5,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"redundant WithMaxAttempts",
+			},
+		},
+		{
+			name: "redundant_max_duration_timer",
+			backoff: WithMaxDuration(1*time.Second,
+				NewMaxDurationTimer(1*time.Millisecond,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"redundant MaxDurationTimer",
+			},
+		},
+		{
+			name: "redundant_consecutive",
+			backoff: WithMaxConsecutiveRetries(3,
+				WithTreatAsSuccess(
+					WithMaxConsecutiveRetries(5,
+						NewExponential(1*time.Second)), io.EOF)),
+			problems: []string{
+				"redundant WithMaxConsecutiveRetries",
+			},
+		},
+		{
+			name: "duration_over_retries",
+			backoff: WithMaxDuration(10*time.Second,
+				WithMaxRetries(5,
+					NewExponential(1*time.Second))),
+		},
+		{
+			name: "retries_over_duration",
+			backoff: WithMaxRetries(5,
+				WithMaxDuration(10*time.Second,
+					NewExponential(1*time.Second))),
+			problems: []string{
+				"WithMaxRetries wraps WithMaxDuration, a retry is counted even if the maximum duration stops",
+			},
+		},
+		{
+			name: "attempts_over_duration_timer",
+			backoff: WithMaxAttempts(5,
+				WithErrorRateBreaker(1*time.Second, 0.5,
+					NewMaxDurationTimer(1*time.Millisecond,
+						NewExponential(1*time.Second)))),
+			problems: []string{
+				"WithMaxAttempts wraps MaxDurationTimer, a retry is counted even if the maximum duration stops",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validate(tc.backoff)
+			if tc.problems == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected %v to be a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Problems, tc.problems) {
+				t.Errorf("expected %q to be %q", verr.Problems, tc.problems)
+			}
+		})
+	}
+}
+
+func ExampleValidate() {
+	b := NewExponential(1 * time.Second)
+	b = WithCappedDuration(10*time.Second, b)
+	b = WithJitter(1*time.Second, false, b)
+
+	if err := Validate(b); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// suspicious backoff composition: WithJitter wraps WithCappedDuration, jitter may exceed the cap
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		backoff Backoff
+		exp     string
+	}{
+		{
+			name:    "nil",
+			backoff: nil,
+			exp:     "",
+		},
+		{
+			name:    "generator",
+			backoff: NewConstant(1 * time.Second),
+			exp:     "Constant(1s)",
+		},
+		{
+			name: "func",
+			backoff: BackoffFunc(func(err error) (time.Duration, error) {
+				return 1 * time.Second, err
+			}),
+			exp: "retry.BackoffFunc",
+		},
+		{
+			name: "chain",
+			backoff: WithRetryable(
+				WithMaxRetries(3,
+					WithJitter(500*time.Millisecond, true,
+						WithCappedDuration(10*time.Second,
+							NewFibonacci(1*time.Second))))),
+			exp: "WithRetryable() -> WithMaxRetries(3) -> WithJitter(500ms, true) -> WithCappedDuration(10s) -> Fibonacci(1s)",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Describe(tc.backoff); got != tc.exp {
+				t.Errorf("expected %q to be %q", got, tc.exp)
+			}
+		})
+	}
+}
+
+func ExampleDescribe() {
+	b := NewExponential(1 * time.Second)
+	b = WithCappedDuration(10*time.Second, b)
+	b = WithMaxRetries(5, b)
+
+	fmt.Println(Describe(b))
+	// Output:
+	// WithMaxRetries(5) -> WithCappedDuration(10s) -> Exponential(1s)
+}
+
+func TestMaxTotalTime(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		backoff Backoff
+		exp     time.Duration
+		ok      bool
+	}{
+		{
+			name:    "unbounded",
+			backoff: NewExponential(1 * time.Second),
+		},
+		{
+			name: "unknown",
+			backoff: WithMaxRetries(3, BackoffFunc(func(err error) (time.Duration, error) {
+				return 1 * time.Second, err
+			})),
+		},
+		{
+			name:    "constant",
+			backoff: WithMaxRetries(3, NewConstant(1*time.Second)),
+			exp:     3 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "exponential",
+			backoff: WithMaxRetries(5, NewExponential(1*time.Second)),
+			exp:     31 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "exponential_from",
+			backoff: WithMaxRetries(3, NewExponentialFrom(1*time.Second, 2)),
+			exp:     28 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "fibonacci",
+			backoff: WithMaxRetries(4, NewFibonacci(1*time.Second)),
+			exp:     11 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "capped",
+			backoff: WithMaxRetries(5, WithCappedDuration(3*time.Second, NewExponential(1*time.Second))),
+			exp:     12 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "max_duration",
+			backoff: WithMaxDuration(10*time.Second, NewExponential(1*time.Second)),
+			exp:     10 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "max_duration_retries",
+			backoff: WithMaxRetries(2, WithMaxDuration(10*time.Second, NewExponential(1*time.Second))),
+			exp:     3 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "jitter",
+			backoff: WithJitter(1*time.Second, false, WithMaxRetries(3, NewConstant(1*time.Second))),
+			exp:     6 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "jitter_percent",
+			backoff: WithMaxRetries(3, WithJitterPercent(50, false, NewConstant(2*time.Second))),
+			exp:     9 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "band",
+			backoff: WithMaxRetries(5, WithBand(2*time.Second, 5*time.Second, NewExponential(1*time.Second))),
+			exp:     18 * time.Second,
+			ok:      true,
+		},
+		{
+			name:    "decaying_jitter",
+			backoff: WithMaxRetries(3, WithDecayingJitter(0.5, 0.5, NewConstant(4*time.Second))),
+			exp:     6*time.Second + 5*time.Second + 4500*time.Millisecond,
+			ok:      true,
+		},
+		{
+			name:    "jitter_cap",
+			backoff: WithMaxRetries(3, WithJitterCap(100*time.Millisecond, 1*time.Second, NewConstant(1*time.Second))),
+			exp:     3300 * time.Millisecond,
+			ok:      true,
+		},
+		{
+			name:    "jitter_unknown_retries",
+			backoff: WithJitter(1*time.Second, false, WithMaxDuration(10*time.Second, NewConstant(1*time.Second))),
+		},
+		{
+			name:    "overflow",
+			backoff: WithMaxRetries(100, NewExponential(1*time.Hour)),
+			exp:     math.MaxInt64,
+			ok:      true,
+		},
+		{
+			name:    "scale",
+			backoff: WithMaxRetries(3, WithScale(func() float64 { return 1 }, NewConstant(1*time.Second))),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			total, ok := MaxTotalTime(tc.backoff)
+			if ok != tc.ok {
+				t.Fatalf("expected %v to be %v", ok, tc.ok)
+			}
+			if total != tc.exp {
+				t.Errorf("expected %v to be %v", total, tc.exp)
+			}
+		})
+	}
+}
+
+func ExampleMaxTotalTime() {
+	b := NewExponential(1 * time.Second)
+	b = WithCappedDuration(10*time.Second, b)
+	b = WithMaxRetries(6, b)
+
+	if total, ok := MaxTotalTime(b); ok {
+		fmt.Println(total)
+	}
+	// Output:
+	// 35s
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultKeyedStateSize is the number of keys WithKeyedState keeps state for.
+const DefaultKeyedStateSize = 1024
+
+type keyedEntry struct {
+	key     string
+	backoff Backoff
+}
+
+type keyedBackoff struct {
+	keyFunc func(ctx context.Context) string
+	factory Factory
+	size    int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+}
+
+// WithKeyedState returns a backoff that keeps separate state per key. The key
+// is derived from the context of the retried operation using keyFunc (e.g. the
+// tenant of a request). On the first use of a key, a fresh backoff is created
+// using factory. This way, the failures of one tenant do not advance the
+// backoff of another one, even though the backoff is shared.
+//
+// The state of at most DefaultKeyedStateSize keys is kept; the least recently
+// used key is evicted once the limit is reached. An evicted key starts over
+// with a fresh backoff. Use WithKeyedStateSize to customize the limit.
+//
+// The context is only available if the backoff is called through
+// NextContext, which Do and the built-in middleware do. Calls to Next use
+// context.Background() to derive the key. It is safe for concurrent use, as
+// long as the created backoffs are.
+func WithKeyedState(keyFunc func(ctx context.Context) string, factory Factory) Backoff {
+	return WithKeyedStateSize(DefaultKeyedStateSize, keyFunc, factory)
+}
+
+// WithKeyedStateSize is like WithKeyedState, but keeps the state of at most
+// size keys. It panics if size is less than 1.
+func WithKeyedStateSize(size int, keyFunc func(ctx context.Context) string, factory Factory) Backoff {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+	return &keyedBackoff{
+		keyFunc: keyFunc,
+		factory: factory,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Next implements Backoff.
+func (b *keyedBackoff) Next(err error) (time.Duration, error) {
+	return b.NextContext(context.Background(), err)
+}
+
+// NextContext implements ContextBackoff.
+func (b *keyedBackoff) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	return nextContext(ctx, b.get(b.keyFunc(ctx)), err)
+}
+
+// get returns the backoff for the given key, creating it if necessary.
+func (b *keyedBackoff) get(key string) Backoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[key]; ok {
+		b.lru.MoveToFront(e)
+		return e.Value.(*keyedEntry).backoff
+	}
+
+	if b.lru.Len() >= b.size {
+		oldest := b.lru.Back()
+		b.lru.Remove(oldest)
+		delete(b.entries, oldest.Value.(*keyedEntry).key)
+	}
+
+	bo := b.factory()
+	b.entries[key] = b.lru.PushFront(&keyedEntry{key: key, backoff: bo})
+	return bo
+}
+
+// Reset implements Resettable. It drops the state of all keys, so that each
+// key starts over with a fresh backoff.
+func (b *keyedBackoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = make(map[string]*list.Element)
+	b.lru.Init()
+}
+
+// String returns a description of the backoff.
+func (b *keyedBackoff) String() string {
+	return fmt.Sprintf("WithKeyedState(%d)", b.size)
+}
@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func tenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func TestWithKeyedState(t *testing.T) {
+	t.Parallel()
+
+	t.Run("separate_state", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithKeyedState(tenantFromContext, func() Backoff {
+			return NewExponential(1 * time.Second)
+		})
+		cb := b.(ContextBackoff)
+
+		ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+		ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+
+		for i, exp := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second} {
+			delay, _ := cb.NextContext(ctxA, nil)
+			if delay != exp {
+				t.Errorf("a: expected %d to be %v, got %v", i, exp, delay)
+			}
+		}
+
+		// tenant b is not affected by the failures of tenant a
+		delay, _ := cb.NextContext(ctxB, nil)
+		if exp := 1 * time.Second; delay != exp {
+			t.Errorf("b: expected %v to be %v", delay, exp)
+		}
+	})
+
+	t.Run("through_middleware", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithCappedDuration(1*time.Minute, WithKeyedState(tenantFromContext, func() Backoff {
+			return NewExponential(1 * time.Second)
+		}))
+		cb := b.(ContextBackoff)
+
+		ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+		ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+
+		cb.NextContext(ctxA, nil)
+		cb.NextContext(ctxA, nil)
+		delay, _ := cb.NextContext(ctxB, nil)
+		if exp := 1 * time.Second; delay != exp {
+			t.Errorf("expected %v to be %v", delay, exp)
+		}
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		t.Parallel()
+
+		var created int
+		b := WithKeyedStateSize(2, tenantFromContext, func() Backoff {
+			created++
+			return NewExponential(1 * time.Second)
+		})
+		cb := b.(ContextBackoff)
+
+		ctx := func(tenant string) context.Context {
+			return context.WithValue(context.Background(), tenantKey{}, tenant)
+		}
+
+		cb.NextContext(ctx("a"), nil)
+		cb.NextContext(ctx("b"), nil)
+		cb.NextContext(ctx("a"), nil) // b is now the least recently used
+		cb.NextContext(ctx("c"), nil) // evicts b
+		if exp := 3; created != exp {
+			t.Errorf("expected %d to be %d", created, exp)
+		}
+
+		delay, _ := cb.NextContext(ctx("a"), nil)
+		if exp := 4 * time.Second; delay != exp {
+			t.Errorf("a: expected %v to be %v", delay, exp)
+		}
+
+		delay, _ = cb.NextContext(ctx("b"), nil)
+		if exp := 1 * time.Second; delay != exp {
+			t.Errorf("b: expected %v to be %v", delay, exp)
+		}
+	})
+
+	t.Run("do", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithKeyedState(tenantFromContext, func() Backoff {
+			return WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+		})
+
+		for _, tenant := range []string{"a", "b"} {
+			ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+
+			var attempts int
+			_ = Do(ctx, b, func(_ context.Context) error {
+				attempts++
+				return RetryableError(fmt.Errorf("oops"))
+			})
+			if exp := 3; attempts != exp {
+				t.Errorf("%s: expected %d to be %d", tenant, attempts, exp)
+			}
+		}
+	})
+}
+
+func ExampleWithKeyedState() {
+	// Keep a separate backoff per tenant, so that the failures of one tenant
+	// do not slow down the retries of another one.
+	b := WithKeyedState(tenantFromContext, func() Backoff {
+		return WithMaxRetries(3, NewExponential(100*time.Millisecond))
+	})
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
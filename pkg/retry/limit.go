@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrRetryLimit is returned, along with the error of the last attempt, when
+// the global retry limit is reached. See SetGlobalRetryLimit.
+var ErrRetryLimit = errors.New("global retry limit reached")
+
+var (
+	// globalRetryLimit is the maximum number of retrying operations. It is
+	// disabled if less than 1.
+	globalRetryLimit int64
+
+	// globalRetriers is the number of operations currently waiting for their
+	// next attempt.
+	globalRetriers int64
+)
+
+// SetGlobalRetryLimit limits the number of operations that are waiting for
+// their next attempt at the same time, process-wide. It caps the pressure of
+// retries during a widespread outage. Once the limit is reached, Do and its
+// variants give up right away with ErrRetryLimit instead of waiting, rather
+// than queueing up. A limit less than 1 removes the limit, which is the
+// default.
+func SetGlobalRetryLimit(n int) {
+	atomic.StoreInt64(&globalRetryLimit, int64(n))
+}
+
+// acquireRetrySlot acquires a slot for waiting for the next attempt. It
+// reports whether a slot is held, which must be released using
+// releaseRetrySlot, and whether the retry is allowed at all.
+func acquireRetrySlot() (held, ok bool) {
+	limit := atomic.LoadInt64(&globalRetryLimit)
+	if limit < 1 {
+		return false, true
+	}
+
+	for {
+		n := atomic.LoadInt64(&globalRetriers)
+		if n >= limit {
+			return false, false
+		}
+		if atomic.CompareAndSwapInt64(&globalRetriers, n, n+1) {
+			return true, true
+		}
+	}
+}
+
+// releaseRetrySlot releases a slot acquired by acquireRetrySlot, if held.
+func releaseRetrySlot(held bool) {
+	if held {
+		atomic.AddInt64(&globalRetriers, -1)
+	}
+}
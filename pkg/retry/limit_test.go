@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetGlobalRetryLimit(t *testing.T) {
+	SetGlobalRetryLimit(2)
+	defer SetGlobalRetryLimit(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// occupy both slots with operations waiting for their next attempt
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = Do(ctx, NewConstant(1*time.Hour), func(_ context.Context) error {
+				return io.EOF
+			})
+		}()
+	}
+	for atomic.LoadInt64(&globalRetriers) < 2 {
+		time.Sleep(1 * time.Millisecond)
+	}
+
+	// a third operation fails fast
+	var calls int
+	var reason StopReason
+	err := Do(context.Background(), NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+		calls++
+		return io.EOF
+	}, OnStop(func(r StopReason, _ error) {
+		reason = r
+	}))
+	if !errors.Is(err, ErrRetryLimit) {
+		t.Errorf("expected %v to be %v", err, ErrRetryLimit)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected %v to be %v", err, io.EOF)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+	if reason != StopRetryLimit {
+		t.Errorf("expected %v to be %v", reason, StopRetryLimit)
+	}
+
+	// an error rooted in a deadline remains a timeout
+	err = Do(context.Background(), NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+		return context.DeadlineExceeded
+	})
+	if !errors.Is(err, ErrRetryLimit) {
+		t.Errorf("expected %v to be %v", err, ErrRetryLimit)
+	}
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Errorf("expected %#v to be a timeout", err)
+	}
+
+	// the helpers keep the error as well
+	err = DoCollectLast(context.Background(), NewConstant(1*time.Nanosecond), 3, func(_ context.Context) error {
+		return io.EOF
+	})
+	if !errors.Is(err, ErrRetryLimit) {
+		t.Errorf("expected %v to be %v", err, ErrRetryLimit)
+	}
+	err = DoBatch(context.Background(), func() Backoff {
+		return WithMaxRetries(0, NewConstant(1*time.Nanosecond))
+	}, NewConstant(1*time.Nanosecond), []int{1}, func(_ context.Context, _ int) error {
+		return io.EOF
+	})
+	if !errors.Is(err, ErrRetryLimit) {
+		t.Errorf("expected %v to be %v", err, ErrRetryLimit)
+	}
+
+	// the slots are released once the operations end
+	cancel()
+	wg.Wait()
+
+	calls = 0
+	err = Do(context.Background(), WithMaxRetries(2, NewConstant(1*time.Nanosecond)), func(_ context.Context) error {
+		calls++
+		return io.EOF
+	})
+	if err != io.EOF {
+		t.Errorf("expected %v to be %v", err, io.EOF)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LogRetries configures a logger, that logs each retry at the info level, along
+// with the attempt, the delay until the next attempt and the error. The
+// attributes returned by the registered extractor are attached to each record
+// (see SetLogAttrsExtractor). A nil logger disables logging, which is the
+// default.
+func LogRetries(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var (
+	logAttrsExtractorMu sync.RWMutex
+	logAttrsExtractor   func(ctx context.Context) []slog.Attr
+)
+
+// SetLogAttrsExtractor registers a function that extracts attributes from a
+// context, e.g. the request ID or trace ID of the surrounding request. They are
+// attached to each record logged for a retry (see LogRetries), which keeps the
+// retry logs correlated with the other logs of the request. A nil function
+// removes the extractor.
+func SetLogAttrsExtractor(fn func(ctx context.Context) []slog.Attr) {
+	logAttrsExtractorMu.Lock()
+	defer logAttrsExtractorMu.Unlock()
+	logAttrsExtractor = fn
+}
+
+// logRetry logs a retry, if a logger is configured.
+func (c *config) logRetry(ctx context.Context, attempt uint64, delay time.Duration, err error) {
+	if c.logger == nil || !c.logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.Uint64("attempt", attempt),
+		slog.Duration("delay", delay),
+		slog.Any("error", err),
+	}
+
+	logAttrsExtractorMu.RLock()
+	extract := logAttrsExtractor
+	logAttrsExtractorMu.RUnlock()
+	if extract != nil {
+		attrs = append(attrs, extract(ctx)...)
+	}
+
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "retrying", attrs...)
+}
@@ -0,0 +1,84 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLogger creates a logger writing text records without a timestamp.
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestLogRetries(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+	Do(context.Background(), b, func(_ context.Context) error {
+		return fmt.Errorf("oops")
+	}, LogRetries(newTestLogger(&buf)))
+
+	exp := "level=INFO msg=retrying attempt=1 delay=1ns error=oops\n" +
+		"level=INFO msg=retrying attempt=2 delay=1ns error=oops\n"
+	if got := buf.String(); got != exp {
+		t.Errorf("expected %q to be %q", got, exp)
+	}
+}
+
+func TestSetLogAttrsExtractor(t *testing.T) {
+	type requestIDKey struct{}
+
+	SetLogAttrsExtractor(func(ctx context.Context) []slog.Attr {
+		id, ok := ctx.Value(requestIDKey{}).(string)
+		if !ok {
+			return nil
+		}
+		return []slog.Attr{slog.String("request_id", id)}
+	})
+	defer SetLogAttrsExtractor(nil)
+
+	var buf bytes.Buffer
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	b := WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+	Do(ctx, b, func(_ context.Context) error {
+		return fmt.Errorf("oops")
+	}, LogRetries(newTestLogger(&buf)))
+
+	if got, want := strings.TrimSpace(buf.String()), "level=INFO msg=retrying attempt=1 delay=1ns error=oops request_id=abc"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func ExampleSetLogAttrsExtractor() {
+	type requestIDKey struct{}
+
+	// attach the request ID to the retry logs
+	SetLogAttrsExtractor(func(ctx context.Context) []slog.Attr {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return []slog.Attr{slog.String("request_id", id)}
+		}
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc")
+	b := WithMaxRetries(3, NewExponential(100*time.Millisecond))
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}, LogRetries(slog.Default())); err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,117 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// Observer is notified about the outcome of each attempt made by Do and its
+// variants. Backoffs implementing Observer, including ones wrapped by
+// middleware, are notified automatically. Further observers can be added using
+// the Observe option.
+type Observer interface {
+	// ObserveSuccess is called after an attempt succeeded.
+	ObserveSuccess()
+
+	// ObserveFailure is called after an attempt failed with the given error.
+	ObserveFailure(err error)
+}
+
+// LatencyObserver is an Observer that is additionally notified about the
+// duration of each attempt.
+type LatencyObserver interface {
+	Observer
+
+	// ObserveLatency is called after each attempt with its duration. It is
+	// called before ObserveSuccess or ObserveFailure.
+	ObserveLatency(d time.Duration)
+}
+
+// Observe adds an observer, that is notified about the outcome of each attempt.
+// It may be given multiple times.
+func Observe(o Observer) Option {
+	return func(c *config) {
+		c.observers = append(c.observers, o)
+	}
+}
+
+// observersOf collects the observers of a retry loop: the ones in the chain of
+// the backoff and the ones added by options.
+func (c *config) observersOf(b Backoff) []Observer {
+	var obs []Observer
+	for _, b := range chain(b) {
+		if o, ok := b.(Observer); ok {
+			obs = append(obs, o)
+		}
+	}
+	return append(obs, c.observers...)
+}
+
+// observe notifies the observers about the outcome and duration of an
+// attempt.
+func observe(obs []Observer, err error, latency time.Duration) {
+	for _, o := range obs {
+		if lo, ok := o.(LatencyObserver); ok {
+			lo.ObserveLatency(latency)
+		}
+		if err == nil {
+			o.ObserveSuccess()
+		} else {
+			o.ObserveFailure(err)
+		}
+	}
+}
+
+// AdaptiveLimiter recommends a concurrency limit based on the outcome of the
+// attempts it observes, so that a caller is able to reduce the parallelism of
+// its workers while a dependency is struggling. It follows the AIMD scheme
+// known from TCP congestion control: each failure halves the limit and each
+// success increases it by one, bounded by 1 and the configured maximum.
+//
+// The limiter is fed by passing it to Do using the Observe option. It is safe
+// for concurrent use, so that a single limiter can be shared by all workers.
+type AdaptiveLimiter struct {
+	l     sync.Mutex
+	max   int
+	limit int
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter, that starts at and never
+// exceeds the given maximum limit, e.g. the size of the worker pool.
+//
+// It panics if max is less than 1.
+func NewAdaptiveLimiter(max int) *AdaptiveLimiter {
+	if max < 1 {
+		panic("max must be at least 1")
+	}
+
+	return &AdaptiveLimiter{
+		max:   max,
+		limit: max,
+	}
+}
+
+// Limit returns the recommended concurrency limit, which is at least 1.
+func (l *AdaptiveLimiter) Limit() int {
+	l.l.Lock()
+	defer l.l.Unlock()
+	return l.limit
+}
+
+// ObserveSuccess implements Observer. It increases the limit by one.
+func (l *AdaptiveLimiter) ObserveSuccess() {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if l.limit < l.max {
+		l.limit++
+	}
+}
+
+// ObserveFailure implements Observer. It halves the limit.
+func (l *AdaptiveLimiter) ObserveFailure(_ error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if l.limit > 1 {
+		l.limit /= 2
+	}
+}
@@ -0,0 +1,136 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testObserver struct {
+	Backoff
+	successes int
+	failures  []error
+}
+
+func (o *testObserver) ObserveSuccess() {
+	o.successes++
+}
+
+func (o *testObserver) ObserveFailure(err error) {
+	o.failures = append(o.failures, err)
+}
+
+func (o *testObserver) Inner() Backoff {
+	return o.Backoff
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo")
+	inChain := &testObserver{Backoff: NewConstant(1 * time.Nanosecond)}
+	byOption := &testObserver{}
+
+	var i int
+	if err := Do(context.Background(), WithMaxRetries(3, inChain), func(_ context.Context) error {
+		i++
+		if i < 3 {
+			return RetryableError(errFoo)
+		}
+		return nil
+	}, Observe(byOption)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range []*testObserver{inChain, byOption} {
+		if o.successes != 1 {
+			t.Errorf("expected %d to be 1", o.successes)
+		}
+		if len(o.failures) != 2 {
+			t.Fatalf("expected %d to be 2", len(o.failures))
+		}
+		for _, err := range o.failures {
+			if !errors.Is(err, errFoo) {
+				t.Errorf("expected %v to be %v", err, errFoo)
+			}
+		}
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := NewAdaptiveLimiter(16)
+	if got := l.Limit(); got != 16 {
+		t.Fatalf("expected %d to be 16", got)
+	}
+
+	// multiplicative decrease
+	for _, want := range []int{8, 4, 2, 1, 1} {
+		l.ObserveFailure(errors.New("foo"))
+		if got := l.Limit(); got != want {
+			t.Errorf("expected %d to be %d", got, want)
+		}
+	}
+
+	// additive increase
+	for i := 0; i < 20; i++ {
+		l.ObserveSuccess()
+	}
+	if got := l.Limit(); got != 16 {
+		t.Errorf("expected %d to be 16", got)
+	}
+
+	// fed by Do
+	if err := Do(context.Background(), WithMaxRetries(2, NewConstant(1*time.Nanosecond)), func(_ context.Context) error {
+		return RetryableError(errors.New("foo"))
+	}, Observe(l)); err == nil {
+		t.Fatal("expected err")
+	}
+	if got := l.Limit(); got != 2 {
+		t.Errorf("expected %d to be 2", got)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewAdaptiveLimiter(0)
+}
+
+func ExampleAdaptiveLimiter() {
+	ctx := context.Background()
+
+	jobs := make(chan string)
+	l := NewAdaptiveLimiter(32)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 32)
+	for job := range jobs {
+		// throttle the workers while the dependency is struggling
+		for len(sem) >= l.Limit() {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			b := WithMaxRetries(3, NewExponential(100*time.Millisecond))
+			if err := Do(ctx, b, func(_ context.Context) error {
+				// TODO: process job
+				return nil
+			}, Observe(l)); err != nil {
+				// handle error
+			}
+		}(job)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,236 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// Option configures the retry loop of Do and its variants.
+type Option func(*config)
+
+// config holds the configuration of a retry loop.
+type config struct {
+	retryContextErrors bool
+	stopContextErrors  bool
+	recoverPanics      bool
+	retryPanics        bool
+	observers          []Observer
+	preferLastErr      bool
+	logger             *slog.Logger
+	onRetry            func(attempt uint64, delay time.Duration, err error)
+	onStop             func(reason StopReason, err error)
+	initialDelay       time.Duration
+	warnInterval       time.Duration
+	onWarn             func(elapsed time.Duration, attempt uint64, err error)
+}
+
+// newConfig creates a configuration from the given options.
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WarnEvery configures a function, that is called periodically while retrying
+// is still going on, e.g. to log a warning about an operation retried by
+// DoForever for a long time. It is called after a failed attempt, at most once
+// per interval, with the time elapsed since the first attempt, the number of
+// the attempt and its error. A non-positive interval or a nil function disables
+// the warning, which is the default.
+func WarnEvery(interval time.Duration, fn func(elapsed time.Duration, attempt uint64, err error)) Option {
+	return func(c *config) {
+		c.warnInterval = interval
+		c.onWarn = fn
+	}
+}
+
+// warner calls the function configured by WarnEvery at most once per interval.
+type warner struct {
+	c     *config
+	begin time.Time
+	last  time.Time
+}
+
+// newWarner creates a warner for a retry loop beginning now.
+func (c *config) newWarner() *warner {
+	now := timeNow()
+	return &warner{c: c, begin: now, last: now}
+}
+
+// warn calls the configured function, if the interval has passed since the
+// last call.
+func (w *warner) warn(attempt uint64, err error) {
+	if w.c.warnInterval <= 0 || w.c.onWarn == nil {
+		return
+	}
+	now := timeNow()
+	if now.Sub(w.last) < w.c.warnInterval {
+		return
+	}
+	w.last = now
+	w.c.onWarn(now.Sub(w.begin), attempt, err)
+}
+
+// RetryContextErrors configures whether context errors (context.Canceled or
+// context.DeadlineExceeded) returned by the retried function are retryable
+// while the context passed to Do is still alive. This is the case if the
+// function uses a context of its own, e.g. a per-attempt timeout or a
+// renewable parent, that expired.
+//
+// When enabled, such errors are marked as retryable (see RetryableError) before
+// they are passed to the backoff, so that they are retried even with
+// WithRetryable. Otherwise, they are passed to the backoff unchanged. In any
+// case, the cancellation of the context passed to Do stops retrying
+// immediately. Defaults to false.
+func RetryContextErrors(retry bool) Option {
+	return func(c *config) {
+		c.retryContextErrors = retry
+	}
+}
+
+// StopOnContextErrors configures whether context errors (context.Canceled or
+// context.DeadlineExceeded) returned by the retried function stop retrying
+// immediately, even though the context passed to Do is still alive, e.g. if a
+// sub-request propagates the expiry of a deadline of its own. When enabled,
+// such an error is returned without consulting the backoff. It takes
+// precedence over RetryContextErrors.
+//
+// By default, a returned context error is only terminal if the context passed
+// to Do is done itself; otherwise, it is passed to the backoff like any other
+// error. Defaults to false.
+func StopOnContextErrors(stop bool) Option {
+	return func(c *config) {
+		c.stopContextErrors = stop
+	}
+}
+
+// isTerminal reports whether the error of a failed attempt stops retrying
+// without consulting the backoff.
+func (c *config) isTerminal(err error) bool {
+	return c.stopContextErrors && isContextError(err)
+}
+
+// InitialDelay configures a delay before the first attempt, e.g. to let a
+// just-created resource propagate before using it. It is independent of the
+// backoff, which still determines the delays between the attempts, and does
+// not count as a retry. If the context is canceled during the initial delay,
+// its error is returned without making any attempt. Defaults to 0, i.e. the
+// first attempt is made right away.
+func InitialDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.initialDelay = d
+	}
+}
+
+// PreferLastErrorOnCancel configures the error returned when the context is
+// canceled while retrying. By default, only the error of the context is
+// returned. When enabled, the error of the last attempt is returned along with
+// it (see errors.Join), so that callers see why it was still retrying. In both
+// cases, errors.Is reports the returned error to be the context error. Defaults
+// to false.
+func PreferLastErrorOnCancel(prefer bool) Option {
+	return func(c *config) {
+		c.preferLastErr = prefer
+	}
+}
+
+// contextErr returns the error for a canceled context, given the error of the
+// last attempt, if any.
+func (c *config) contextErr(ctx context.Context, lastErr error) error {
+	if !c.preferLastErr || lastErr == nil {
+		return ctx.Err()
+	}
+	return asTimeout(errors.Join(ctx.Err(), lastErr))
+}
+
+// classify prepares the error of a failed attempt before it is passed to the
+// backoff.
+func (c *config) classify(ctx context.Context, err error) error {
+	if c.retryPanics {
+		if perr, ok := err.(*PanicError); ok {
+			return RetryableError(perr)
+		}
+	}
+	if c.retryContextErrors && ctx.Err() == nil && isContextError(err) {
+		var rerr *retryableError
+		if !errors.As(err, &rerr) {
+			return RetryableError(err)
+		}
+	}
+	return err
+}
+
+// isContextError reports whether the error is caused by a context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// PanicError is returned for a panic of the retried function that was
+// recovered (see RecoverPanics).
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error returns the error string.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value, if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// RecoverPanics configures whether panics of the retried function are
+// recovered. When enabled, a panic is turned into a *PanicError, carrying the
+// panic value and the stack trace, and retrying stops with it. The backoff is
+// not consulted. Defaults to false, i.e. panics are propagated.
+func RecoverPanics(enabled bool) Option {
+	return func(c *config) {
+		c.recoverPanics = enabled
+	}
+}
+
+// RetryPanics configures whether recovered panics of the retried function are
+// retried. When enabled, panics are recovered (see RecoverPanics) and the
+// resulting *PanicError is marked as retryable before it is passed to the
+// backoff, so that the panic is retried up to the limits of the backoff.
+// Defaults to false.
+//
+// Use it with care: a panic often indicates a bug or a corrupted state, that
+// retrying does not fix and may even make worse. It is meant for known
+// transient panics, e.g. of a racy third-party library.
+func RetryPanics(retry bool) Option {
+	return func(c *config) {
+		c.retryPanics = retry
+	}
+}
+
+// call calls the retried function, recovering panics if configured. The
+// returned bool reports whether the function panicked.
+func (c *config) call(ctx context.Context, f RetryFunc) (panicked bool, err error) {
+	if !c.recoverPanics && !c.retryPanics {
+		return false, f(ctx)
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			panicked = true
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return false, f(ctx)
+}
@@ -0,0 +1,391 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry/retrytest"
+)
+
+func TestRetryContextErrors(t *testing.T) {
+	t.Parallel()
+
+	// f uses a per-attempt timeout that always expires
+	f := func(calls *int) RetryFunc {
+		return func(ctx context.Context) error {
+			*calls++
+			ctx, cancel := context.WithTimeout(ctx, 1*time.Millisecond)
+			defer cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryable(WithMaxRetries(3, NewConstant(1*time.Nanosecond)))
+
+		var calls int
+		err := Do(context.Background(), b, f(&calls))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryable(WithMaxRetries(3, NewConstant(1*time.Nanosecond)))
+
+		var calls int
+		err := Do(context.Background(), b, f(&calls), RetryContextErrors(true))
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := calls, 4; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("parent_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryable(NewConstant(1 * time.Nanosecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		err := Do(ctx, b, func(ctx context.Context) error {
+			calls++
+			cancel()
+			return ctx.Err()
+		}, RetryContextErrors(true))
+		if err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestStopOnContextErrors(t *testing.T) {
+	t.Parallel()
+
+	// f makes a sub-request with a timeout of its own, that always expires
+	// while the parent context is still alive
+	subRequest := func(calls *int) RetryFunc {
+		return func(ctx context.Context) error {
+			*calls++
+			ctx, cancel := context.WithTimeout(ctx, 1*time.Millisecond)
+			defer cancel()
+			<-ctx.Done()
+			return fmt.Errorf("sub-request: %w", ctx.Err())
+		}
+	}
+
+	cases := []struct {
+		name  string
+		opts  []Option
+		calls int
+	}{
+		{
+			name:  "default",
+			opts:  nil,
+			calls: 4,
+		},
+		{
+			name:  "stop",
+			opts:  []Option{StopOnContextErrors(true)},
+			calls: 1,
+		},
+		{
+			name:  "precedence",
+			opts:  []Option{StopOnContextErrors(true), RetryContextErrors(true)},
+			calls: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+			var calls int
+			err := Do(context.Background(), b, subRequest(&calls), tc.opts...)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+			}
+			if got, want := calls, tc.calls; got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		})
+	}
+
+	t.Run("own_context", func(t *testing.T) {
+		t.Parallel()
+
+		// the error of the loop's own context is terminal by default
+		ctx, cancel := context.WithCancel(context.Background())
+		b := NewConstant(1 * time.Nanosecond)
+
+		var calls int
+		err := Do(ctx, b, func(ctx context.Context) error {
+			calls++
+			cancel()
+			return ctx.Err()
+		})
+		if err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestPreferLastErrorOnCancel(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo")
+
+	cases := []struct {
+		name    string
+		prefer  bool
+		lastErr bool
+	}{
+		{
+			name:    "default",
+			prefer:  false,
+			lastErr: false,
+		},
+		{
+			name:    "enabled",
+			prefer:  true,
+			lastErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			b := NewConstant(1 * time.Hour)
+
+			err := Do(ctx, b, func(_ context.Context) error {
+				cancel()
+				return RetryableError(errFoo)
+			}, PreferLastErrorOnCancel(tc.prefer))
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected %v to be %v", err, context.Canceled)
+			}
+			if got := errors.Is(err, errFoo); got != tc.lastErr {
+				t.Errorf("expected %v to be %v", got, tc.lastErr)
+			}
+		})
+	}
+
+	t.Run("no_attempt", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := Do(ctx, NewConstant(1*time.Hour), func(_ context.Context) error {
+			return nil
+		}, PreferLastErrorOnCancel(true))
+		if err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestInitialDelay(t *testing.T) {
+	t.Parallel()
+
+	t.Run("delays_first_attempt", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		start := time.Now()
+		var first time.Duration
+		attempts, err := DoCount(context.Background(), b, func(_ context.Context) error {
+			if first == 0 {
+				first = time.Since(start)
+			}
+			return io.EOF
+		}, InitialDelay(50*time.Millisecond))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if first < 50*time.Millisecond {
+			t.Errorf("expected %v to be at least %v", first, 50*time.Millisecond)
+		}
+		// the initial delay does not consume a retry
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		attempts, err := DoCount(ctx, NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+			return nil
+		}, InitialDelay(1*time.Hour))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if attempts != 0 {
+			t.Errorf("expected no attempts, got %d", attempts)
+		}
+		if elapsed := time.Since(start); elapsed >= 1*time.Second {
+			t.Errorf("expected %v to be less than %v", elapsed, 1*time.Second)
+		}
+	})
+}
+
+func TestRecoverPanics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("recover", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewConstant(1 * time.Nanosecond)
+
+		var calls int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			calls++
+			panic("oops")
+		}, RecoverPanics(true))
+
+		var perr *PanicError
+		if !errors.As(err, &perr) {
+			t.Fatalf("expected %v to be a *PanicError", err)
+		}
+		if got, want := perr.Value, "oops"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if !strings.Contains(string(perr.Stack), "TestRecoverPanics") {
+			t.Errorf("expected stack to contain the test, got %s", perr.Stack)
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryable(WithMaxRetries(3, NewConstant(1*time.Nanosecond)))
+
+		var calls int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			calls++
+			if calls < 3 {
+				panic(io.EOF)
+			}
+			return nil
+		}, RetryPanics(true))
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := calls, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("retry_limit", func(t *testing.T) {
+		t.Parallel()
+
+		b := WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+
+		var calls int
+		err := Do(context.Background(), b, func(_ context.Context) error {
+			calls++
+			panic(io.EOF)
+		}, RetryPanics(true))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if got, want := calls, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if v := recover(); v != "oops" {
+				t.Errorf("expected panic to be propagated, got %v", v)
+			}
+		}()
+		_ = Do(context.Background(), NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+			panic("oops")
+		})
+	})
+}
+
+func TestWarnEvery(t *testing.T) {
+	c := retrytest.NewFakeClock(time.Now())
+	SetClock(c)
+	defer SetClock(nil)
+
+	type warning struct {
+		elapsed time.Duration
+		attempt uint64
+	}
+
+	// each attempt takes 1m and is retried right away
+	b := BackoffFunc(func(err error) (time.Duration, error) {
+		return 0, err
+	})
+
+	var i int
+	var warnings []warning
+	err := DoForever(context.Background(), b, func(_ context.Context) error {
+		c.Advance(1 * time.Minute)
+		i++
+		if i < 10 {
+			return io.EOF
+		}
+		return nil
+	}, WarnEvery(3*time.Minute, func(elapsed time.Duration, attempt uint64, err error) {
+		if err != io.EOF {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		warnings = append(warnings, warning{elapsed, attempt})
+	}))
+	if err != nil {
+		t.Fatalf("expected no err, got %v", err)
+	}
+
+	exp := []warning{
+		{3 * time.Minute, 3},
+		{6 * time.Minute, 6},
+		{9 * time.Minute, 9},
+	}
+	if got, want := fmt.Sprint(warnings), fmt.Sprint(exp); got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
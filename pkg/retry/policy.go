@@ -0,0 +1,238 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// policy describes a standard backoff stack, e.g. read from the environment.
+type policy struct {
+	// kind is the generator, one of "exponential" (default), "fibonacci" or
+	// "constant".
+	kind string
+	base time.Duration
+
+	// max caps the delays, if greater than 0.
+	max time.Duration
+
+	// maxRetries limits the number of retries, if hasMaxRetries is true.
+	maxRetries    uint64
+	hasMaxRetries bool
+
+	// maxDuration limits the total duration, if greater than 0.
+	maxDuration time.Duration
+
+	// jitter is the fraction of the jitter applied to the delays, e.g. 0.2 for
+	// ±20%.
+	jitter float64
+}
+
+// backoff validates the policy and composes its backoff. The middleware is
+// applied in the following order, from the innermost to the outermost one:
+// jitter, cap, maximum retries and maximum duration. This way, the jitter
+// never pushes a delay beyond the cap or the maximum duration, and no retry is
+// counted once the maximum duration is exhausted (see Validate).
+func (p *policy) backoff() (Backoff, error) {
+	if p.base <= 0 {
+		return nil, errors.New("base must be greater than 0")
+	}
+	if p.max < 0 {
+		return nil, errors.New("max must not be negative")
+	}
+	if p.maxDuration < 0 {
+		return nil, errors.New("max duration must not be negative")
+	}
+	if p.jitter < 0 || p.jitter > 1 {
+		return nil, errors.New("jitter must be between 0 and 1")
+	}
+
+	var b Backoff
+	switch p.kind {
+	case "", "exponential":
+		b = NewExponential(p.base)
+	case "fibonacci":
+		b = NewFibonacci(p.base)
+	case "constant":
+		b = NewConstant(p.base)
+	default:
+		return nil, fmt.Errorf("unknown type %q", p.kind)
+	}
+
+	if p.jitter > 0 {
+		b = WithJitterPercent(uint64(math.Round(p.jitter*100)), false, b)
+	}
+	if p.max > 0 {
+		b = WithCappedDuration(p.max, b)
+	}
+	if p.hasMaxRetries {
+		b = WithMaxRetries(p.maxRetries, b)
+	}
+	if p.maxDuration > 0 {
+		b = WithMaxDuration(p.maxDuration, b)
+	}
+	return b, nil
+}
+
+// NewFromEnv creates a backoff from environment variables, so that operators
+// are able to tune the retry behavior without code changes. The following
+// variables are read, each prefixed with prefix and an underscore:
+//
+//	TYPE         the backoff: exponential (default), fibonacci or constant
+//	BASE         the base delay, e.g. 100ms (required)
+//	MAX          the maximum delay (see WithCappedDuration)
+//	MAX_DURATION the maximum total duration (see WithMaxDuration)
+//	MAX_RETRIES  the maximum number of retries (see WithMaxRetries)
+//	JITTER       the jitter as a fraction, e.g. 0.2 for ±20% (see
+//	             WithJitterPercent)
+//
+// Durations are parsed using time.ParseDuration. Unset or empty variables are
+// ignored, apart from BASE. The middleware is composed in the order
+// recommended by Validate. An error is returned for missing or invalid values.
+func NewFromEnv(prefix string) (Backoff, error) {
+	var p policy
+	var err error
+
+	env := func(name string) (string, string, bool) {
+		key := prefix + "_" + name
+		v := os.Getenv(key)
+		return key, v, v != ""
+	}
+	duration := func(name string, dst *time.Duration) {
+		if key, v, ok := env(name); ok && err == nil {
+			if *dst, err = time.ParseDuration(v); err != nil {
+				err = fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+
+	if _, v, ok := env("TYPE"); ok {
+		p.kind = v
+	}
+	if key, _, ok := env("BASE"); !ok {
+		return nil, fmt.Errorf("%s is required", key)
+	}
+	duration("BASE", &p.base)
+	duration("MAX", &p.max)
+	duration("MAX_DURATION", &p.maxDuration)
+	if key, v, ok := env("MAX_RETRIES"); ok && err == nil {
+		if p.maxRetries, err = strconv.ParseUint(v, 10, 64); err != nil {
+			err = fmt.Errorf("%s: %w", key, err)
+		}
+		p.hasMaxRetries = true
+	}
+	if key, v, ok := env("JITTER"); ok && err == nil {
+		if p.jitter, err = strconv.ParseFloat(v, 64); err != nil {
+			err = fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := p.backoff()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", prefix, err)
+	}
+	return b, nil
+}
+
+// PolicyFromMap creates a backoff from a structured description, e.g. read
+// from a configuration file. The package stays agnostic of the serialization
+// format: the caller unmarshals the configuration into a map, e.g.:
+//
+//	{"type": "exponential", "base": "100ms", "max": "30s", "maxRetries": 5, "jitter": "0.2"}
+//
+// The following keys are supported:
+//
+//	type         the backoff: exponential (default), fibonacci or constant
+//	base         the base delay (required)
+//	max          the maximum delay (see WithCappedDuration)
+//	maxDuration  the maximum total duration (see WithMaxDuration)
+//	maxRetries   the maximum number of retries (see WithMaxRetries)
+//	jitter       the jitter as a fraction, e.g. 0.2 for ±20% (see
+//	             WithJitterPercent)
+//
+// Durations are given as strings parsed by time.ParseDuration. Numbers may be
+// given as numbers or strings. The middleware is composed in the same order as
+// by NewFromEnv. An error is returned for unknown keys, missing or invalid
+// values.
+func PolicyFromMap(m map[string]interface{}) (Backoff, error) {
+	var p policy
+	for key, v := range m {
+		var err error
+		switch key {
+		case "type":
+			s, ok := v.(string)
+			if !ok {
+				err = fmt.Errorf("expected a string, got %T", v)
+			}
+			p.kind = s
+		case "base":
+			p.base, err = parseDuration(v)
+		case "max":
+			p.max, err = parseDuration(v)
+		case "maxDuration":
+			p.maxDuration, err = parseDuration(v)
+		case "maxRetries":
+			p.maxRetries, err = parseUint(v)
+			p.hasMaxRetries = true
+		case "jitter":
+			p.jitter, err = parseFloat(v)
+		default:
+			err = errors.New("unknown key")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if _, ok := m["base"]; !ok {
+		return nil, errors.New("base is required")
+	}
+	return p.backoff()
+}
+
+// parseDuration parses a duration given as a string.
+func parseDuration(v interface{}) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected a duration string, got %T", v)
+	}
+	return time.ParseDuration(s)
+}
+
+// parseFloat parses a number given as a number or a string.
+func parseFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+// parseUint parses an unsigned integer given as a number or a string.
+func parseUint(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v >= 1<<64 {
+			return 0, fmt.Errorf("expected an unsigned integer, got %v", v)
+		}
+		return uint64(v), nil
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("expected an unsigned integer, got %v", v)
+		}
+		return uint64(v), nil
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	return 0, fmt.Errorf("expected an unsigned integer, got %T", v)
+}
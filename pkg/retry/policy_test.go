@@ -0,0 +1,241 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		exp  string
+		err  string
+	}{
+		{
+			name: "base",
+			env: map[string]string{
+				"RETRY_BASE": "100ms",
+			},
+			exp: "Exponential(100ms)",
+		},
+		{
+			name: "full",
+			env: map[string]string{
+				"RETRY_TYPE":         "fibonacci",
+				"RETRY_BASE":         "100ms",
+				"RETRY_MAX":          "10s",
+				"RETRY_MAX_DURATION": "1m",
+				"RETRY_MAX_RETRIES":  "5",
+				"RETRY_JITTER":       "0.2",
+			},
+			exp: "WithMaxDuration(1m0s) -> WithMaxRetries(5) -> WithCappedDuration(10s) -> WithJitterPercent(20, false) -> Fibonacci(100ms)",
+		},
+		{
+			name: "zero_retries",
+			env: map[string]string{
+				"RETRY_TYPE":        "constant",
+				"RETRY_BASE":        "1s",
+				"RETRY_MAX_RETRIES": "0",
+			},
+			exp: "WithMaxRetries(0) -> Constant(1s)",
+		},
+		{
+			name: "missing_base",
+			env:  map[string]string{},
+			err:  "RETRY_BASE is required",
+		},
+		{
+			name: "invalid_duration",
+			env: map[string]string{
+				"RETRY_BASE": "100",
+			},
+			err: `RETRY_BASE: time: missing unit in duration "100"`,
+		},
+		{
+			name: "invalid_retries",
+			env: map[string]string{
+				"RETRY_BASE":        "100ms",
+				"RETRY_MAX_RETRIES": "-1",
+			},
+			err: `RETRY_MAX_RETRIES: strconv.ParseUint: parsing "-1": invalid syntax`,
+		},
+		{
+			name: "invalid_jitter",
+			env: map[string]string{
+				"RETRY_BASE":   "100ms",
+				"RETRY_JITTER": "1.5",
+			},
+			err: "RETRY: jitter must be between 0 and 1",
+		},
+		{
+			name: "unknown_type",
+			env: map[string]string{
+				"RETRY_TYPE": "linear",
+				"RETRY_BASE": "100ms",
+			},
+			err: `RETRY: unknown type "linear"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"TYPE", "BASE", "MAX", "MAX_DURATION", "MAX_RETRIES", "JITTER"} {
+				t.Setenv("RETRY_"+name, tc.env["RETRY_"+name])
+			}
+
+			b, err := NewFromEnv("RETRY")
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected %v to be %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no err, got %v", err)
+			}
+			if got := Describe(b); got != tc.exp {
+				t.Errorf("expected %q to be %q", got, tc.exp)
+			}
+			if err := Validate(b); err != nil {
+				t.Errorf("expected no validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyFromMap(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		m    map[string]interface{}
+		exp  string
+		err  string
+	}{
+		{
+			name: "base",
+			m:    map[string]interface{}{"base": "100ms"},
+			exp:  "Exponential(100ms)",
+		},
+		{
+			name: "full",
+			m: map[string]interface{}{
+				"type":        "exponential",
+				"base":        "100ms",
+				"max":         "30s",
+				"maxDuration": "5m",
+				"maxRetries":  float64(5),
+				"jitter":      "0.2",
+			},
+			exp: "WithMaxDuration(5m0s) -> WithMaxRetries(5) -> WithCappedDuration(30s) -> WithJitterPercent(20, false) -> Exponential(100ms)",
+		},
+		{
+			name: "numbers",
+			m: map[string]interface{}{
+				"type":       "constant",
+				"base":       "1s",
+				"maxRetries": "3",
+				"jitter":     0.5,
+			},
+			exp: "WithMaxRetries(3) -> WithJitterPercent(50, false) -> Constant(1s)",
+		},
+		{
+			name: "missing_base",
+			m:    map[string]interface{}{"max": "1s"},
+			err:  "base is required",
+		},
+		{
+			name: "unknown_key",
+			m:    map[string]interface{}{"base": "1s", "retries": 3},
+			err:  "retries: unknown key",
+		},
+		{
+			name: "invalid_duration",
+			m:    map[string]interface{}{"base": float64(100)},
+			err:  "base: expected a duration string, got float64",
+		},
+		{
+			name: "invalid_retries",
+			m:    map[string]interface{}{"base": "1s", "maxRetries": 1.5},
+			err:  "maxRetries: expected an unsigned integer, got 1.5",
+		},
+		{
+			name: "overflowing_retries",
+			m:    map[string]interface{}{"base": "1s", "maxRetries": float64(1 << 64)},
+			err:  "maxRetries: expected an unsigned integer, got 1.8446744073709552e+19",
+		},
+		{
+			name: "invalid_jitter",
+			m:    map[string]interface{}{"base": "1s", "jitter": "-0.1"},
+			err:  "jitter must be between 0 and 1",
+		},
+		{
+			name: "invalid_type",
+			m:    map[string]interface{}{"type": "linear", "base": "1s"},
+			err:  `unknown type "linear"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := PolicyFromMap(tc.m)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("expected %v to be %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no err, got %v", err)
+			}
+			if got := Describe(b); got != tc.exp {
+				t.Errorf("expected %q to be %q", got, tc.exp)
+			}
+		})
+	}
+}
+
+func ExamplePolicyFromMap() {
+	// e.g. unmarshaled from a JSON or YAML configuration
+	m := map[string]interface{}{
+		"type":       "exponential",
+		"base":       "100ms",
+		"max":        "30s",
+		"maxRetries": 5,
+	}
+
+	b, err := PolicyFromMap(m)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(Describe(b))
+	// Output:
+	// WithMaxRetries(5) -> WithCappedDuration(30s) -> Exponential(100ms)
+}
+
+func ExampleNewFromEnv() {
+	ctx := context.Background()
+
+	// e.g. FETCH_BASE=100ms FETCH_MAX=10s FETCH_MAX_RETRIES=5 FETCH_JITTER=0.2
+	b, err := NewFromEnv("FETCH")
+	if err != nil {
+		// fall back to a default
+		b = WithMaxRetries(5, NewExponential(100*time.Millisecond))
+	}
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
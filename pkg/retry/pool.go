@@ -0,0 +1,52 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+var exponentialPool = sync.Pool{
+	New: func() interface{} {
+		return &exponentialBackoff{}
+	},
+}
+
+// AcquireExponential returns an exponential backoff from a pool. It behaves
+// exactly like one created by NewExponential, but allows high-throughput
+// servers to recycle backoffs instead of allocating a new one per request. The
+// backoff should be handed back using Release once it is no longer needed.
+//
+// It panics if the given base is less than zero.
+func AcquireExponential(base time.Duration) Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	b := exponentialPool.Get().(*exponentialBackoff)
+	b.base = base
+	b.pooled = true
+	return b
+}
+
+// Release resets a backoff acquired from a pool (see AcquireExponential) and
+// puts it back into the pool. A released backoff must not be used afterwards,
+// neither directly nor through middleware wrapping it, since it may be handed
+// out again at any time.
+//
+// For a backoff returned by WithIdempotencyScope, Release drops the reference
+// to the shared state of the scope instead.
+//
+// Backoffs that are neither acquired from a pool nor scoped are ignored, e.g.
+// one created by NewExponential. Releasing a backoff again has no effect.
+func Release(b Backoff) {
+	if sb, ok := b.(*scopedBackoff); ok {
+		sb.release()
+		return
+	}
+	if eb, ok := b.(*exponentialBackoff); ok && eb.pooled {
+		eb.pooled = false
+		eb.start = 0
+		eb.Reset()
+		exponentialPool.Put(eb)
+	}
+}
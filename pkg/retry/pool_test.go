@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAcquireExponential(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 3; i++ {
+		b := AcquireExponential(1 * time.Second)
+
+		results := make([]time.Duration, 3)
+		for j := range results {
+			results[j], _ = b.Next(nil)
+		}
+
+		// a recycled backoff behaves like a new one
+		if exp := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(results, exp) {
+			t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", results, exp)
+		}
+
+		Release(b)
+	}
+
+	// not poolable, must not panic
+	Release(NewConstant(1 * time.Second))
+
+	// not acquired from the pool, must be left untouched
+	b := NewExponential(1 * time.Second)
+	b.Next(nil)
+	Release(b)
+	if delay, _ := b.Next(nil); delay != 2*time.Second {
+		t.Errorf("expected %v to be %v", delay, 2*time.Second)
+	}
+
+	// releasing twice must not put the backoff into the pool twice
+	b = AcquireExponential(1 * time.Second)
+	Release(b)
+	Release(b)
+	if b1, b2 := AcquireExponential(1*time.Second), AcquireExponential(1*time.Second); b1 == b2 {
+		t.Error("expected distinct backoffs")
+	}
+}
+
+func BenchmarkAcquireExponential(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		bo := AcquireExponential(1 * time.Second)
+		bo.Next(nil)
+		Release(bo)
+	}
+}
+
+func ExampleAcquireExponential() {
+	ctx := context.Background()
+
+	b := AcquireExponential(100 * time.Millisecond)
+	defer Release(b)
+
+	if err := Do(ctx, WithMaxRetries(3, b), func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
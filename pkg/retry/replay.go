@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stopRecord is the record of a stop. See WithRecorder.
+const stopRecord = "stop"
+
+// WithRecorder records the delays of the next backoff to w, so that they can
+// be replayed later using NewReplay, e.g. to reproduce a timing-dependent bug
+// with the exact delays (including jitter) seen in production. Each delay is
+// written on a line of its own, formatted as a time.Duration; a stop is
+// written as "stop".
+//
+// Errors writing to w are ignored, in order not to affect the retries. The
+// writes are serialized, so that the backoff is safe for concurrent use, as
+// long as the next backoff is.
+func WithRecorder(w io.Writer, next Backoff) Backoff {
+	var l sync.Mutex
+
+	return wrap("WithRecorder", "", next, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, next, err)
+		if isPeek(ctx) {
+			return delay, err
+		}
+
+		l.Lock()
+		defer l.Unlock()
+		if IsStopped(delay) {
+			fmt.Fprintln(w, stopRecord)
+		} else {
+			fmt.Fprintln(w, delay)
+		}
+		return delay, err
+	})
+}
+
+type replayBackoff struct {
+	l       sync.Mutex
+	scanner *bufio.Scanner
+	done    bool
+}
+
+// NewReplay creates a backoff that replays the delays recorded by WithRecorder
+// from r, in order. It stops once the recording ends, or at a recorded stop.
+// The recording is read lazily. An invalid record is treated like the end of
+// the recording.
+func NewReplay(r io.Reader) Backoff {
+	return &replayBackoff{
+		scanner: bufio.NewScanner(r),
+	}
+}
+
+// Next implements Backoff. It is safe for concurrent use.
+func (b *replayBackoff) Next(err error) (time.Duration, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.done || !b.scanner.Scan() {
+		b.done = true
+		return Stop, err
+	}
+
+	record := strings.TrimSpace(b.scanner.Text())
+	if record == stopRecord {
+		b.done = true
+		return Stop, err
+	}
+	delay, perr := time.ParseDuration(record)
+	if perr != nil || delay < 0 {
+		b.done = true
+		return Stop, err
+	}
+	return delay, err
+}
+
+// String returns a description of the backoff.
+func (b *replayBackoff) String() string {
+	return "Replay()"
+}
@@ -0,0 +1,113 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRecorder(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	b := WithRecorder(&buf, WithMaxRetries(3, WithJitter(500*time.Millisecond, false, NewExponential(1*time.Second))))
+
+	var recorded []time.Duration
+	for {
+		delay, _ := b.Next(nil)
+		if IsStopped(delay) {
+			break
+		}
+		recorded = append(recorded, delay)
+	}
+
+	if got, want := strings.Count(buf.String(), "\n"), 4; got != want {
+		t.Fatalf("expected %v to be %v", got, want)
+	}
+	if !strings.HasSuffix(buf.String(), "stop\n") {
+		t.Errorf("expected %q to end with a stop", buf.String())
+	}
+
+	// replay the exact delays
+	r := NewReplay(&buf)
+	for i, want := range recorded {
+		if delay, _ := r.Next(nil); delay != want {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, delay, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if delay, _ := r.Next(nil); !IsStopped(delay) {
+			t.Errorf("expected %v to stop", delay)
+		}
+	}
+}
+
+func TestNewReplay(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		in   string
+		exp  []time.Duration
+	}{
+		{
+			name: "empty",
+			in:   "",
+			exp:  nil,
+		},
+		{
+			name: "end",
+			in:   "1s\n1.5s\n",
+			exp:  []time.Duration{1 * time.Second, 1500 * time.Millisecond},
+		},
+		{
+			name: "stop",
+			in:   "1s\nstop\n2s\n",
+			exp:  []time.Duration{1 * time.Second},
+		},
+		{
+			name: "invalid",
+			in:   "1s\noops\n2s\n",
+			exp:  []time.Duration{1 * time.Second},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewReplay(strings.NewReader(tc.in))
+			var got []time.Duration
+			for i := 0; i < 10; i++ {
+				delay, _ := b.Next(nil)
+				if IsStopped(delay) {
+					break
+				}
+				got = append(got, delay)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.exp) {
+				t.Errorf("expected %v to be %v", got, tc.exp)
+			}
+		})
+	}
+}
+
+func ExampleNewReplay() {
+	ctx := context.Background()
+
+	// e.g. recorded in production using WithRecorder
+	recording := strings.NewReader("1.2s\n2.1s\n3.9s\nstop\n")
+
+	b := NewReplay(recording)
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: reproduce the bug
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
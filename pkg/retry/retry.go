@@ -10,51 +10,553 @@
 // customize the built-in backoff with your own custom logic. Additionally,
 // callers specify which errors are retryable by wrapping them. This is helpful
 // with complex operations where only certain results should retry.
+//
+// All backoffs and middleware of this package are safe for concurrent use. Note
+// that the state of a backoff, e.g. the count of retries of WithMaxRetries or
+// the start time of WithMaxDuration, is shared by all of its users. Sharing a
+// backoff among concurrent operations hence limits them as a whole. To retry
+// operations independently, create a backoff per operation, e.g. using a
+// Factory. A BackoffFunc and custom middleware are only safe for concurrent use
+// if their implementation is.
 package retry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+var (
+	// ErrNoProgress is returned by DoProgress, along with the error of the
+	// last attempt, when the attempts stopped making progress.
+	ErrNoProgress = errors.New("no progress")
+
+	// ErrUnhealthy is the error passed to the backoff when an attempt was
+	// skipped, because the probe reported the dependency to be unhealthy. See
+	// DoWithProbe.
+	ErrUnhealthy = errors.New("dependency unhealthy")
+)
+
 // RetryFunc is a function passed to retry.
 type RetryFunc func(ctx context.Context) error //revive:disable-line
 
-// Do wraps a function with a backoff to retry. The provided context is the same
-// context passed to the RetryFunc.
-func Do(ctx context.Context, b Backoff, f RetryFunc) error {
+// Do wraps a function with a backoff to retry. The provided context is passed
+// to the RetryFunc, carrying the number of the attempt (see
+// AttemptFromContext).
+//
+// If the returned error is rooted in a deadline (i.e. errors.Is(err,
+// context.DeadlineExceeded) is true), it implements net.Error with Timeout()
+// reporting true.
+//
+// The reason why retrying stopped is reported through the OnStop option.
+//
+// If a global retry limit is set (see SetGlobalRetryLimit) and reached, Do
+// gives up instead of waiting for the next attempt.
+//
+// If the backoff implements ContextBackoff, the context is passed on to it.
+//
+// If the retried function returns an error marked as unrecoverable (see
+// Unrecoverable), Do stops retrying without consulting the backoff.
+//
+// If a SpanRecorder is present in the context (see SpanRecorderFromContext),
+// each retry is recorded through it.
+//
+// The outcome of each attempt is reported to the observers of the retry loop
+// (see Observer).
+//
+// The behavior of the retry loop can be customized using options.
+//
+// Do waits between attempts using the clock set by SetClock. The real clock
+// uses a time.Timer only, which makes it compatible with the virtual time of
+// testing/synctest.
+func Do(ctx context.Context, b Backoff, f RetryFunc, opts ...Option) error {
+	return do(ctx, b, f, newConfig(opts), nil)
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the number of the current attempt, starting with
+// 1, within a RetryFunc called by Do. This is useful e.g. for logging or
+// idempotency keys. It returns 0 if the context does not stem from Do.
+func AttemptFromContext(ctx context.Context) uint64 {
+	attempt, _ := ctx.Value(attemptKey{}).(uint64)
+	return attempt
+}
+
+// do implements the retry loop. If ctl is not nil, the loop is controlled by
+// it (see DoControllable).
+func do(ctx context.Context, b Backoff, f RetryFunc, c *config, ctl *Control) error {
+	rec := SpanRecorderFromContext(ctx)
+	obs := c.observersOf(b)
+	bctx, stop := withStopReasonRecorder(ctx)
+
+	if c.initialDelay > 0 {
+		if err := sleep(ctx, c.initialDelay, nil); err != nil {
+			return c.contextErr(ctx, nil)
+		}
+	}
+
+	var attempt uint64
+	var lastErr error
+	warn := c.newWarner()
 	for {
+		if ctl != nil {
+			if err := ctl.waitResumed(ctx); err != nil {
+				return c.contextErr(ctx, lastErr)
+			}
+		}
+
 		// Return immediately if ctx is canceled
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return c.contextErr(ctx, lastErr)
 		default:
 		}
 
-		err := f(ctx)
+		attempt++
+		start := timeNow()
+		panicked, err := c.call(context.WithValue(ctx, attemptKey{}, attempt), f)
+		if err != nil && isSuccess(b, err) {
+			err = nil
+		}
+		observe(obs, err, timeSince(start))
 		if err == nil {
 			return nil
 		}
+		if panicked && !c.retryPanics {
+			return err
+		}
+		if c.isTerminal(err) {
+			return asTimeout(err)
+		}
+		if uerr, ok := isUnrecoverable(err); ok {
+			c.stopped(StopUnrecoverable, uerr)
+			return asTimeout(uerr)
+		}
 
-		delay, err := b.Next(err)
+		stop.reason = StopUnknown
+		delay, err := nextContext(bctx, b, c.classify(ctx, err))
+		lastErr = err
 		if IsStopped(delay) {
-			return err
+			c.stopped(stop.reason, err)
+			return asTimeout(err)
+		}
+
+		held, ok := acquireRetrySlot()
+		if !ok {
+			c.stopped(StopRetryLimit, err)
+			return asTimeout(fmt.Errorf("%w: %w", ErrRetryLimit, err))
 		}
 
+		if rec != nil {
+			rec.RecordRetry(attempt, delay, err)
+		}
+		c.logRetry(ctx, attempt, delay, err)
+		if c.onRetry != nil {
+			c.onRetry(attempt, delay, err)
+		}
+		warn.warn(attempt, err)
+
 		// ctx.Done() has priority, so we test it alone first
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			releaseRetrySlot(held)
+			return c.contextErr(ctx, lastErr)
 		default:
 		}
 
-		t := time.NewTimer(delay)
-		select {
-		case <-ctx.Done():
-			t.Stop()
-			return ctx.Err()
-		case <-t.C:
-			continue
+		var wake <-chan struct{}
+		if ctl != nil {
+			wake = ctl.beginSleep()
+		}
+
+		if err := sleep(ctx, delay, wake); err != nil {
+			releaseRetrySlot(held)
+			return c.contextErr(ctx, lastErr)
+		}
+		releaseRetrySlot(held)
+
+		if ctl != nil {
+			ctl.endSleep()
 		}
 	}
 }
+
+// timeoutError marks an error rooted in a deadline as timeout. It implements
+// net.Error, so that generic handling of network timeouts works with the errors
+// returned by Do.
+type timeoutError struct {
+	err error
+}
+
+// asTimeout wraps the error into a timeoutError, if it is rooted in a deadline
+// and is no timeout by itself.
+func asTimeout(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(interface{ Timeout() bool }); ok {
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &timeoutError{err}
+}
+
+// Unwrap implements error wrapping.
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+// Error returns the error string.
+func (e *timeoutError) Error() string {
+	return e.err.Error()
+}
+
+// Timeout implements net.Error. It always reports true.
+func (e *timeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary implements net.Error. It always reports true.
+func (e *timeoutError) Temporary() bool {
+	return true
+}
+
+// DoForever is like Do, but explicitly intends to retry infinitely. It should be
+// used with a backoff that never stops on its own (i.e. without WithMaxRetries
+// or WithMaxDuration), so that retrying is only bounded by the context. Using
+// DoForever instead of Do makes the intent clear and distinguishes it from an
+// accidentally unbounded retry loop.
+//
+// To notice an operation that keeps failing, use the WarnEvery option, e.g. to
+// log a warning every minute that it is still being retried.
+//
+// If the backoff does stop nonetheless (e.g. on a non retryable error), the
+// error is returned just like with Do.
+func DoForever(ctx context.Context, b Backoff, f RetryFunc, opts ...Option) error {
+	return Do(ctx, b, f, opts...)
+}
+
+// DoBudgetSplit is like Do, but divides a total time budget across the
+// attempts. Each attempt gets a context with a timeout of the remaining budget
+// divided by the number of remaining attempts, computed right before the
+// attempt starts. This prevents an early slow attempt from consuming the whole
+// budget and starving later attempts. The budget includes the time spent
+// waiting between attempts.
+//
+// At most maxAttempts attempts are made, regardless of the backoff. Once the
+// budget is exhausted, context.DeadlineExceeded is returned. Note that a
+// per-attempt timeout is reported to the backoff via the error returned by f;
+// when used with WithRetryable, f needs to mark it as retryable or the
+// RetryContextErrors option must be enabled. It panics if maxAttempts is less
+// than 1.
+func DoBudgetSplit(ctx context.Context, b Backoff, total time.Duration, maxAttempts int, f RetryFunc, opts ...Option) error {
+	if maxAttempts < 1 {
+		panic("maxAttempts must be greater than 0")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, total)
+	defer cancel()
+	end := timeNow().Add(total)
+
+	var attempt int
+	return Do(ctx, WithMaxRetries(uint64(maxAttempts-1), b), func(ctx context.Context) error {
+		timeout := end.Sub(timeNow()) / time.Duration(maxAttempts-attempt)
+		attempt++
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx)
+	}, opts...)
+}
+
+// DoAttemptFraction is like Do, but gives each attempt a fraction of the time
+// remaining until the deadline of the context, computed right before the
+// attempt starts. For example, with a fraction of 0.25, each attempt may take
+// at most 25% of the remaining time. This way, a hung attempt cannot consume
+// the whole budget, while the timeout still adapts as the deadline approaches.
+// If the context has no deadline, the attempts are not limited.
+//
+// Note that a per-attempt timeout is reported to the backoff via the error
+// returned by f; when used with WithRetryable, f needs to mark it as retryable
+// or the RetryContextErrors option must be enabled. It panics if fraction is
+// not within (0, 1].
+func DoAttemptFraction(ctx context.Context, b Backoff, fraction float64, f RetryFunc, opts ...Option) error {
+	if fraction <= 0 || fraction > 1 {
+		panic("fraction must be within (0, 1]")
+	}
+
+	return Do(ctx, b, func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return f(ctx)
+		}
+
+		timeout := time.Duration(float64(deadline.Sub(timeNow())) * fraction)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx)
+	}, opts...)
+}
+
+// DoWithAttemptTimeout is like Do, but bounds each attempt by its own timeout,
+// separate from the deadline of the context. This way, a single hung attempt
+// cannot block the whole retry loop. Each attempt gets a child context with the
+// given timeout, which is canceled once the attempt returns.
+//
+// An attempt failing while its timeout has expired is marked as retryable (see
+// RetryableError), so that it is retried even with WithRetryable. A
+// cancellation of the parent context still stops the retry loop. It panics if
+// attemptTimeout is not greater than 0.
+func DoWithAttemptTimeout(ctx context.Context, b Backoff, attemptTimeout time.Duration, f RetryFunc, opts ...Option) error {
+	if attemptTimeout <= 0 {
+		panic("attemptTimeout must be greater than 0")
+	}
+
+	return Do(ctx, b, func(ctx context.Context) error {
+		actx, cancel := context.WithTimeout(ctx, attemptTimeout)
+		defer cancel()
+
+		err := f(actx)
+		if err == nil || ctx.Err() != nil || actx.Err() == nil {
+			return err
+		}
+		if _, ok := err.(*retryableError); ok {
+			return err
+		}
+		return RetryableError(err)
+	}, opts...)
+}
+
+// DoCollectLast is like Do, but keeps the errors of the last n failed attempts
+// and returns them joined (see errors.Join) when it gives up. The errors are
+// ordered from oldest to newest. Errors marked with RetryableError are
+// unwrapped. The newest one is the error returned by Do, so that its
+// properties are preserved, e.g. the error of the context if it was canceled,
+// or ErrRetryLimit (see SetGlobalRetryLimit).
+//
+// Only the last n errors are kept, so that long running or infinite retries do
+// not grow the memory unboundedly. It panics if n is less than 1.
+func DoCollectLast(ctx context.Context, b Backoff, n int, f RetryFunc, opts ...Option) error {
+	if n < 1 {
+		panic("n must be greater than 0")
+	}
+
+	// ring buffer of the last n errors; next is the index of the oldest one once
+	// the buffer is full
+	errs := make([]error, 0, n)
+	var next int
+
+	err := Do(ctx, b, func(ctx context.Context) error {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+
+		rerr := err
+		if r, ok := rerr.(*retryableError); ok {
+			rerr = r.err
+		}
+		if len(errs) < n {
+			errs = append(errs, rerr)
+		} else {
+			errs[next] = rerr
+			next = (next + 1) % n
+		}
+		return err
+	}, opts...)
+	if err == nil {
+		return nil
+	}
+
+	ordered := make([]error, 0, len(errs)+1)
+	ordered = append(ordered, errs[next:]...)
+	ordered = append(ordered, errs[:next]...)
+
+	// the error returned by Do usually wraps the one of the last attempt, which
+	// it replaces then
+	if i := len(ordered) - 1; i >= 0 && errors.Is(err, ordered[i]) {
+		ordered = ordered[:i]
+	}
+	return errors.Join(append(ordered, err)...)
+}
+
+// DoWorkBudget is like Do, but bounds the time spent working, i.e. the sum of
+// the wall time spent inside f across all attempts. The time spent waiting
+// between attempts is not included. This models a compute budget, in contrast
+// to WithMaxDuration, which includes the waiting time.
+//
+// Once the accumulated work time reaches the budget, no further attempt is
+// started and the error of the last attempt is returned. An attempt that is
+// already running is not interrupted.
+func DoWorkBudget(ctx context.Context, b Backoff, budget time.Duration, f RetryFunc, opts ...Option) error {
+	var spent time.Duration
+
+	wb := wrap("DoWorkBudget", fmt.Sprint(budget), b, func(ctx context.Context, err error) (time.Duration, error) {
+		if spent >= budget {
+			recordStop(ctx, StopMaxDuration)
+			return Stop, err
+		}
+		return nextContext(ctx, b, err)
+	})
+
+	return Do(ctx, wb, func(ctx context.Context) error {
+		start := timeNow()
+		err := f(ctx)
+		spent += timeSince(start)
+		return err
+	}, opts...)
+}
+
+// DoProgress is like Do, but gives up if the attempts do not make progress,
+// e.g. for retrying a download that is stuck. Besides the error, f reports its
+// progress, such as the total number of bytes transferred so far. An attempt
+// advances the progress, if it reports a progress of at least minDelta more
+// than the last advancing attempt (or zero, initially).
+//
+// Once maxStalls consecutive failed attempts did not advance the progress, no
+// further attempt is made and ErrNoProgress is returned, joined with the error
+// of the last attempt. Otherwise, the backoff decides as usual. It panics if
+// maxStalls is less than 1.
+func DoProgress(ctx context.Context, b Backoff, f func(ctx context.Context) (progress int64, err error), minDelta int64, maxStalls int, opts ...Option) error {
+	if maxStalls < 1 {
+		panic("maxStalls must be greater than 0")
+	}
+
+	var last int64
+	var stalls int
+	var stuck bool
+
+	pb := wrap("DoProgress", "", b, func(ctx context.Context, err error) (time.Duration, error) {
+		if stuck {
+			return Stop, fmt.Errorf("%w: %w", ErrNoProgress, err)
+		}
+		return nextContext(ctx, b, err)
+	})
+
+	return Do(ctx, pb, func(ctx context.Context) error {
+		progress, err := f(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if progress-last >= minDelta {
+			last = progress
+			stalls = 0
+		} else {
+			stalls++
+			stuck = stalls >= maxStalls
+		}
+		return err
+	}, opts...)
+}
+
+// DoWithProbe is like Do, but consults a health probe before each attempt. The
+// function is only called if probe reports the dependency to be healthy.
+// Otherwise, the attempt is skipped and the backoff receives ErrUnhealthy
+// marked as retryable, so that it waits before probing again. This avoids
+// wasting attempts against a dependency known to be down, e.g. by a circuit
+// breaker.
+//
+// Skipped attempts count like failed ones, e.g. towards WithMaxRetries. If the
+// backoff stops on a skipped attempt, ErrUnhealthy is returned.
+func DoWithProbe(ctx context.Context, b Backoff, probe func(ctx context.Context) bool, f RetryFunc, opts ...Option) error {
+	return Do(ctx, b, func(ctx context.Context) error {
+		if !probe(ctx) {
+			return RetryableError(ErrUnhealthy)
+		}
+		return f(ctx)
+	}, opts...)
+}
+
+// DoWithPrev is like Do, but passes the error of the previous attempt to f, or
+// nil on the first attempt. This allows to adapt an attempt to the failure of
+// the previous one, e.g. to restart a download from the beginning if resuming
+// it failed, without keeping state in a closure. Like the error returned by Do,
+// prevErr is unwrapped if it was marked as retryable (see RetryableError).
+func DoWithPrev(ctx context.Context, b Backoff, f func(ctx context.Context, prevErr error) error, opts ...Option) error {
+	var prevErr error
+	return Do(ctx, b, func(ctx context.Context) error {
+		err := f(ctx, prevErr)
+		prevErr = err
+		if r, ok := err.(*retryableError); ok {
+			prevErr = r.err
+		}
+		return err
+	}, opts...)
+}
+
+// DoWithHooks is like Do, but calls onRetry before each wait for the next
+// attempt, with the number of the failed attempt (1-based), the delay until the
+// next attempt and the error that triggered the retry. It is not called after
+// a success or after the final failure. This gives a place to emit metrics or
+// logs without wiring them into f. A nil onRetry is ignored.
+func DoWithHooks(ctx context.Context, b Backoff, f RetryFunc, onRetry func(attempt uint64, delay time.Duration, err error), opts ...Option) error {
+	c := newConfig(opts)
+	c.onRetry = onRetry
+	return do(ctx, b, f, c, nil)
+}
+
+// DoCount is like Do, but additionally returns the number of attempts, i.e.
+// how often f was called. It is 1 on an immediate success and 0 if the context
+// was canceled before the first attempt.
+func DoCount(ctx context.Context, b Backoff, f RetryFunc, opts ...Option) (attempts uint64, err error) {
+	err = Do(ctx, b, func(ctx context.Context) error {
+		attempts++
+		return f(ctx)
+	}, opts...)
+	return attempts, err
+}
+
+// Classification is the retryability decision on a failed attempt. See
+// DoClassified.
+type Classification struct {
+	// Attempt is the number of the attempt (1-based).
+	Attempt uint64
+
+	// Err is the error returned by the attempt.
+	Err error
+
+	// Retryable reports whether the error was deemed retryable.
+	Retryable bool
+}
+
+// DoClassified is like Do, but decides on the retryability of errors using
+// classify and records each decision. Only errors for which classify reports
+// true are retried; for any other error, retrying stops right away without
+// consulting the backoff. The classifications of all failed attempts are
+// returned in order, on success as well as on failure, which helps to debug
+// why an operation was or was not retried.
+func DoClassified(ctx context.Context, b Backoff, classify func(err error) bool, f RetryFunc, opts ...Option) ([]Classification, error) {
+	var cs []Classification
+	var attempt uint64
+	retryable := true
+
+	rb := wrap("DoClassified", "", b, func(ctx context.Context, err error) (time.Duration, error) {
+		if !retryable {
+			recordStop(ctx, StopNonRetryable)
+			return Stop, err
+		}
+		return nextContext(ctx, b, err)
+	})
+
+	err := Do(ctx, rb, func(ctx context.Context) error {
+		attempt++
+		retryable = true
+
+		err := f(ctx)
+		if err != nil {
+			retryable = classify(err)
+			cs = append(cs, Classification{
+				Attempt:   attempt,
+				Err:       err,
+				Retryable: retryable,
+			})
+		}
+		return err
+	}, opts...)
+	return cs, err
+}
@@ -0,0 +1,71 @@
+//go:build go1.25
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// The tests in this file run in a synctest bubble, where time is virtual. Do
+// waits using time.NewTimer, which advances instantly once all goroutines of
+// the bubble are blocked. This way, the timing of a backoff can be validated
+// without real delays.
+
+func TestDoSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		b := WithMaxRetries(5, NewExponential(1*time.Second))
+
+		start := time.Now()
+		var elapsed []time.Duration
+		err := Do(ctx, b, func(_ context.Context) error {
+			elapsed = append(elapsed, time.Since(start))
+			return RetryableError(errors.New("oops"))
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+
+		// 1s, 2s, 4s, 8s and 16s between the attempts
+		exp := []time.Duration{0, 1 * time.Second, 3 * time.Second, 7 * time.Second, 15 * time.Second, 31 * time.Second}
+		if len(elapsed) != len(exp) {
+			t.Fatalf("expected %v to be %v", elapsed, exp)
+		}
+		for i := range exp {
+			if elapsed[i] != exp[i] {
+				t.Errorf("attempt %d: expected %v to be %v", i+1, elapsed[i], exp[i])
+			}
+		}
+	})
+}
+
+func TestDoSynctestDeadline(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		b := NewExponential(1 * time.Second)
+
+		start := time.Now()
+		var attempts int
+		err := Do(ctx, b, func(_ context.Context) error {
+			attempts++
+			return errors.New("oops")
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+
+		// attempts after 0s, 1s, 3s and 7s; the wait for the next one (8s) is
+		// interrupted by the deadline
+		if got, want := attempts, 4; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := time.Since(start), 10*time.Second; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
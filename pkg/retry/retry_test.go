@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -107,6 +109,33 @@ func TestDo(t *testing.T) {
 		}
 	})
 
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(1, BackoffFunc(func(err error) (time.Duration, error) {
+			return 1 * time.Nanosecond, err
+		}))
+
+		err := Do(ctx, b, func(_ context.Context) error {
+			return fmt.Errorf("sub request: %w", context.DeadlineExceeded)
+		})
+
+		nerr, ok := err.(net.Error)
+		if !ok {
+			t.Fatalf("expected %#v to implement net.Error", err)
+		}
+		if !nerr.Timeout() {
+			t.Errorf("expected %v to be a timeout", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := err.Error(), "sub request: context deadline exceeded"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+
 	t.Run("context_canceled", func(t *testing.T) {
 		t.Parallel()
 
@@ -125,6 +154,819 @@ func TestDo(t *testing.T) {
 	})
 }
 
+func TestDoForever(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bounded_by_context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		var i int
+		if err := DoForever(ctx, NewConstant(1*time.Millisecond), func(_ context.Context) error {
+			i++
+			return fmt.Errorf("oops")
+		}); err != context.DeadlineExceeded {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+
+		if i < 2 {
+			t.Errorf("expected %v to be retried", i)
+		}
+	})
+
+	t.Run("exit_no_error", func(t *testing.T) {
+		t.Parallel()
+
+		var i int
+		if err := DoForever(context.Background(), NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+			i++
+			if i < 5 {
+				return fmt.Errorf("oops")
+			}
+			return nil
+		}); err != nil {
+			t.Fatal("expected no err")
+		}
+
+		if got, want := i, 5; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestAttemptFromContext(t *testing.T) {
+	t.Parallel()
+
+	if got := AttemptFromContext(context.Background()); got != 0 {
+		t.Errorf("expected %v to be 0", got)
+	}
+
+	ctx := context.Background()
+	b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+	var attempts []uint64
+	err := Do(ctx, b, func(ctx context.Context) error {
+		attempts = append(attempts, AttemptFromContext(ctx))
+		return RetryableError(errors.New("oops"))
+	})
+	if err == nil {
+		t.Fatal("expected err")
+	}
+
+	exp := []uint64{1, 2, 3, 4}
+	if !reflect.DeepEqual(attempts, exp) {
+		t.Errorf("expected %v to be %v", attempts, exp)
+	}
+}
+
+func TestDoWithAttemptTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retries_hung_attempts", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+
+		var attempts int
+		err := DoWithAttemptTimeout(ctx, b, 10*time.Millisecond, func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				<-ctx.Done() // hang until the attempt times out
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("expected %v to be nil", err)
+		}
+		if got, want := attempts, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+
+		var attempts int
+		err := DoWithAttemptTimeout(ctx, b, 10*time.Millisecond, func(ctx context.Context) error {
+			attempts++
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := attempts, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("not_retryable", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(NewConstant(1 * time.Nanosecond))
+		errOops := errors.New("oops")
+
+		var attempts int
+		err := DoWithAttemptTimeout(ctx, b, 1*time.Second, func(_ context.Context) error {
+			attempts++
+			return errOops
+		})
+		if err != errOops {
+			t.Errorf("expected %v to be %v", err, errOops)
+		}
+		if got, want := attempts, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("parent_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var attempts int
+		err := DoWithAttemptTimeout(ctx, b, 1*time.Hour, func(ctx context.Context) error {
+			attempts++
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+		if got, want := attempts, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic")
+			}
+		}()
+		_ = DoWithAttemptTimeout(context.Background(), NewConstant(1*time.Second), 0, func(_ context.Context) error {
+			return nil
+		})
+	})
+}
+
+func TestDoBudgetSplit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("split", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		start := time.Now()
+		var timeouts []time.Duration
+		err := DoBudgetSplit(ctx, b, 400*time.Millisecond, 4, func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			timeouts = append(timeouts, time.Until(deadline))
+			<-ctx.Done() // hang until the attempt times out
+			return ctx.Err()
+		})
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+
+		if got, want := len(timeouts), 4; got != want {
+			t.Fatalf("expected %v to be %v", got, want)
+		}
+		for i, timeout := range timeouts {
+			if min, max := 80*time.Millisecond, 100*time.Millisecond; timeout < min || timeout > max {
+				t.Errorf("attempt %d: expected %v to be between %v and %v", i+1, timeout, min, max)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+			t.Errorf("expected %v to be within the budget", elapsed)
+		}
+	})
+
+	t.Run("fast_attempt", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var timeouts []time.Duration
+		err := DoBudgetSplit(ctx, b, 400*time.Millisecond, 2, func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			timeouts = append(timeouts, time.Until(deadline))
+			return fmt.Errorf("oops") // fails fast, leaving budget to the next attempt
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+
+		if got, want := len(timeouts), 2; got != want {
+			t.Fatalf("expected %v to be %v", got, want)
+		}
+		if min := 300 * time.Millisecond; timeouts[1] < min {
+			t.Errorf("expected %v to be at least %v", timeouts[1], min)
+		}
+	})
+}
+
+func TestDoAttemptFraction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fraction_of_remaining", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		var timeouts []time.Duration
+		err := DoAttemptFraction(ctx, b, 0.25, func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("expected a deadline")
+			}
+			timeouts = append(timeouts, time.Until(deadline))
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+
+		// 250ms of 1s, ~188ms of ~750ms, ~141ms of ~563ms
+		exp := []time.Duration{250 * time.Millisecond, 188 * time.Millisecond, 141 * time.Millisecond}
+		if len(timeouts) != len(exp) {
+			t.Fatalf("expected %v to be %v", timeouts, exp)
+		}
+		for i := range exp {
+			if timeouts[i] > exp[i] || timeouts[i] < exp[i]-50*time.Millisecond {
+				t.Errorf("attempt %d: expected %v to be about %v", i+1, timeouts[i], exp[i])
+			}
+		}
+	})
+
+	t.Run("no_deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		err := DoAttemptFraction(ctx, b, 0.5, func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("expected no deadline")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+	})
+
+	t.Run("invalid_fraction", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		_ = DoAttemptFraction(context.Background(), NewConstant(1*time.Nanosecond), 1.5, func(_ context.Context) error {
+			return nil
+		})
+	})
+}
+
+func TestDoCollectLast(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exit_no_error", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		if err := DoCollectLast(ctx, b, 2, func(_ context.Context) error {
+			i++
+			if i < 3 {
+				return fmt.Errorf("oops %d", i)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+	})
+
+	t.Run("keeps_last", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(WithMaxRetries(4, NewConstant(1*time.Nanosecond)))
+
+		var i int
+		err := DoCollectLast(ctx, b, 3, func(_ context.Context) error {
+			i++
+			return RetryableError(fmt.Errorf("oops %d", i))
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+
+		// oldest to newest
+		if got, want := err.Error(), "oops 3\noops 4\noops 5"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+
+	t.Run("fewer_than_n", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+
+		err := DoCollectLast(ctx, b, 3, func(_ context.Context) error {
+			return io.EOF
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if got, want := err.Error(), "EOF\nEOF"; got != want {
+			t.Errorf("expected %q to be %q", got, want)
+		}
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		b := NewConstant(5 * time.Second)
+
+		err := DoCollectLast(ctx, b, 3, func(_ context.Context) error {
+			return io.EOF
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("prefer_last_error", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		b := NewConstant(5 * time.Second)
+
+		err := DoCollectLast(ctx, b, 3, func(_ context.Context) error {
+			return io.EOF
+		}, PreferLastErrorOnCancel(true))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestDoWorkBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var attempts int
+		err := DoWorkBudget(ctx, b, 25*time.Millisecond, func(_ context.Context) error {
+			attempts++
+			time.Sleep(10 * time.Millisecond)
+			return io.EOF
+		})
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		// 2 attempts if they took longer than expected
+		if attempts < 2 || attempts > 3 {
+			t.Errorf("expected %v to be 2 or 3", attempts)
+		}
+	})
+
+	t.Run("excludes_sleep", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(20 * time.Millisecond)
+
+		var attempts int
+		err := DoWorkBudget(ctx, b, 10*time.Millisecond, func(_ context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return io.EOF
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+	})
+}
+
+func TestDoProgress(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		progress []int64
+		attempts int
+		err      error
+	}{
+		{
+			name:     "advancing",
+			progress: []int64{10, 20, 30, 40, 50},
+			attempts: 5,
+			err:      io.EOF,
+		},
+		{
+			name:     "stuck",
+			progress: []int64{10, 20, 20, 25, 30, 40},
+			attempts: 4,
+			err:      ErrNoProgress,
+		},
+		{
+			name:     "recovering",
+			progress: []int64{10, 10, 20, 20, 30},
+			attempts: 5,
+			err:      io.EOF,
+		},
+		{
+			name:     "stuck_initially",
+			progress: []int64{0, 5, 10},
+			attempts: 2,
+			err:      ErrNoProgress,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			b := WithMaxRetries(uint64(len(tc.progress)-1), NewConstant(1*time.Nanosecond))
+
+			var attempts int
+			err := DoProgress(ctx, b, func(_ context.Context) (int64, error) {
+				progress := tc.progress[attempts]
+				attempts++
+				return progress, io.EOF
+			}, 10, 2)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("expected %v to be %v", err, io.EOF)
+			}
+			if got, want := attempts, tc.attempts; got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDoWithProbe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("skips_unhealthy", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(NewConstant(1 * time.Nanosecond))
+
+		var probes, calls int
+		err := DoWithProbe(ctx, b, func(_ context.Context) bool {
+			probes++
+			return probes > 2
+		}, func(_ context.Context) error {
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := probes, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := calls, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+
+		var calls int
+		err := DoWithProbe(ctx, b, func(_ context.Context) bool {
+			return false
+		}, func(_ context.Context) error {
+			calls++
+			return nil
+		})
+		if !errors.Is(err, ErrUnhealthy) {
+			t.Errorf("expected %v to be %v", err, ErrUnhealthy)
+		}
+		if got, want := calls, 0; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestUnrecoverable(t *testing.T) {
+	t.Parallel()
+
+	errFatal := errors.New("fatal")
+
+	cases := []struct {
+		name string
+		err  error
+		exp  string
+	}{
+		{
+			name: "plain",
+			err:  Unrecoverable(errFatal),
+			exp:  "fatal",
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("op: %w", Unrecoverable(errFatal)),
+			exp:  "op: unrecoverable: fatal",
+		},
+		{
+			name: "retryable",
+			err:  RetryableError(Unrecoverable(errFatal)),
+			exp:  "retryable: unrecoverable: fatal",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			b := WithRetryable(WithMaxRetries(100, NewConstant(1*time.Nanosecond)))
+
+			var attempts int
+			err := Do(ctx, b, func(_ context.Context) error {
+				attempts++
+				if attempts == 2 {
+					return tc.err
+				}
+				return RetryableError(io.EOF)
+			})
+			if !errors.Is(err, errFatal) {
+				t.Errorf("expected %v to be %v", err, errFatal)
+			}
+			if err.Error() != tc.exp {
+				t.Errorf("expected %q to be %q", err.Error(), tc.exp)
+			}
+			if attempts != 2 {
+				t.Errorf("expected 2 attempts, got %d", attempts)
+			}
+		})
+	}
+
+	if err := Unrecoverable(nil); err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+}
+
+func TestDoWithPrev(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+	errs := []error{errors.New("a"), errors.New("b"), errors.New("c")}
+	var prevs []error
+	err := DoWithPrev(ctx, b, func(_ context.Context, prevErr error) error {
+		prevs = append(prevs, prevErr)
+		if len(prevs) > len(errs) {
+			return nil
+		}
+		return RetryableError(errs[len(prevs)-1])
+	})
+	if err != nil {
+		t.Fatalf("expected no err, got %v", err)
+	}
+
+	exp := []error{nil, errs[0], errs[1], errs[2]}
+	if len(prevs) != len(exp) {
+		t.Fatalf("expected %v to be %v", prevs, exp)
+	}
+	for i := range exp {
+		if prevs[i] != exp[i] {
+			t.Errorf("attempt %d: expected %v to be %v", i+1, prevs[i], exp[i])
+		}
+	}
+}
+
+func TestDoWithHooks(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oops")
+
+	type call struct {
+		attempt uint64
+		delay   time.Duration
+		err     error
+	}
+
+	cases := []struct {
+		name    string
+		retries uint64
+		succeed bool
+		exp     []call
+	}{
+		{
+			name:    "success",
+			retries: 3,
+			succeed: true,
+			exp: []call{
+				{1, 1 * time.Millisecond, errTest},
+				{2, 2 * time.Millisecond, errTest},
+			},
+		},
+		{
+			name:    "failure",
+			retries: 2,
+			exp: []call{
+				{1, 1 * time.Millisecond, errTest},
+				{2, 2 * time.Millisecond, errTest},
+			},
+		},
+		{
+			name:    "no_retries",
+			retries: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			b := WithMaxRetries(tc.retries, NewExponential(1*time.Millisecond))
+
+			var attempts int
+			var calls []call
+			err := DoWithHooks(ctx, b, func(_ context.Context) error {
+				attempts++
+				if tc.succeed && attempts == len(tc.exp)+1 {
+					return nil
+				}
+				return RetryableError(errTest)
+			}, func(attempt uint64, delay time.Duration, err error) {
+				calls = append(calls, call{attempt, delay, err})
+			})
+			if tc.succeed && err != nil {
+				t.Fatalf("expected no err, got %v", err)
+			}
+			if !tc.succeed && !errors.Is(err, errTest) {
+				t.Fatalf("expected %v to be %v", err, errTest)
+			}
+
+			if len(calls) != len(tc.exp) {
+				t.Fatalf("expected %v to be %v", calls, tc.exp)
+			}
+			for i, c := range tc.exp {
+				if calls[i].attempt != c.attempt || calls[i].delay != c.delay || !errors.Is(calls[i].err, c.err) {
+					t.Errorf("call %d: expected %v to be %v", i+1, calls[i], c)
+				}
+			}
+		})
+	}
+}
+
+func TestDoCount(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		failures int
+		attempts uint64
+		err      error
+	}{
+		{
+			name:     "first_try",
+			ctx:      context.Background(),
+			failures: 0,
+			attempts: 1,
+		},
+		{
+			name:     "after_retries",
+			ctx:      context.Background(),
+			failures: 2,
+			attempts: 3,
+		},
+		{
+			name:     "exhausted",
+			ctx:      context.Background(),
+			failures: 10,
+			attempts: 4,
+			err:      io.EOF,
+		},
+		{
+			name:     "canceled",
+			ctx:      canceled,
+			failures: 0,
+			attempts: 0,
+			err:      context.Canceled,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+			var calls int
+			attempts, err := DoCount(tc.ctx, b, func(_ context.Context) error {
+				calls++
+				if calls <= tc.failures {
+					return io.EOF
+				}
+				return nil
+			})
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+			if attempts != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestDoClassified(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := WithMaxRetries(5, NewConstant(1*time.Nanosecond))
+
+	errs := []error{io.ErrUnexpectedEOF, io.ErrUnexpectedEOF, io.EOF}
+	var i int
+	cs, err := DoClassified(ctx, b, func(err error) bool {
+		return err == io.ErrUnexpectedEOF
+	}, func(_ context.Context) error {
+		err := errs[i]
+		i++
+		return err
+	})
+	if err != io.EOF {
+		t.Errorf("expected %v to be %v", err, io.EOF)
+	}
+
+	exp := []Classification{
+		{Attempt: 1, Err: io.ErrUnexpectedEOF, Retryable: true},
+		{Attempt: 2, Err: io.ErrUnexpectedEOF, Retryable: true},
+		{Attempt: 3, Err: io.EOF, Retryable: false},
+	}
+	if !reflect.DeepEqual(cs, exp) {
+		t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", cs, exp)
+	}
+}
+
 func ExampleDo_simple() {
 	ctx := context.Background()
 
@@ -0,0 +1,162 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRetryResult is the error passed to the backoff when the result of an
+	// operation requires another attempt. See DoResultRetry.
+	ErrRetryResult = errors.New("result requires retry")
+
+	// ErrSlowAttempt is the error passed to the backoff when an attempt
+	// succeeded, but was too slow. See DoLatencyRetry.
+	ErrSlowAttempt = errors.New("attempt too slow")
+)
+
+// DoValue wraps a function that produces a value with a backoff to retry. It
+// is like Do, but threads the result of f through, so that no variable needs
+// to be captured by the closure. On success, the result is returned with a
+// nil error. Otherwise, the zero value is returned along with the error.
+func DoValue[T any](ctx context.Context, b Backoff, f func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var result T
+	err := Do(ctx, b, func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// DoResultRetry wraps a function that produces a value with a backoff to
+// retry. In addition to failed attempts (non-nil error), an attempt is retried
+// if retry reports true for its result. This is the value-based analog to the
+// error-based retry, e.g. for polling a long running operation that reports
+// "try again" via its result.
+//
+// For a result that requires a retry, the backoff receives ErrRetryResult
+// marked as retryable. If the backoff stops on such an attempt, its result is
+// returned along with the error. On success, the result is returned with a nil
+// error.
+func DoResultRetry[T any](ctx context.Context, b Backoff, f func(ctx context.Context) (T, error), retry func(T) bool, opts ...Option) (T, error) {
+	var result T
+	err := Do(ctx, b, func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		if err != nil {
+			return err
+		}
+		if retry(result) {
+			return RetryableError(ErrRetryResult)
+		}
+		return nil
+	}, opts...)
+	return result, err
+}
+
+// DoValueWithCleanup wraps a function that produces a value with a backoff to
+// retry. Besides the value and the error, f returns a cleanup function, that
+// releases the resources acquired during the attempt (e.g. a half-open
+// connection). It may be nil.
+//
+// The cleanup function of a failed attempt is run right after the attempt,
+// before the next one is started. This also applies to an attempt that failed
+// because its context was canceled. The result of a failed attempt is
+// discarded, hence the cleanup is run for the last failed attempt as well. The
+// cleanup function of the successful attempt is never run, as the caller takes
+// ownership of the returned value.
+//
+// On success, the result is returned with a nil error. Otherwise, the zero
+// value is returned along with the error.
+func DoValueWithCleanup[T any](ctx context.Context, b Backoff, f func(ctx context.Context) (T, error, func()), opts ...Option) (T, error) {
+	var result T
+	err := Do(ctx, b, func(ctx context.Context) error {
+		r, err, cleanup := f(ctx)
+		if err != nil {
+			if cleanup != nil {
+				cleanup()
+			}
+			return err
+		}
+		result = r
+		return nil
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// DoValueIf wraps a function that produces a value with a backoff to retry.
+// Only errors for which shouldRetry reports true are retried; for any other
+// error, retrying stops right away without consulting the backoff. The
+// cancellation and stop semantics are the same as with Do.
+//
+// On success, the result is returned with a nil error. Otherwise, the zero
+// value is returned along with the error.
+func DoValueIf[T any](ctx context.Context, b Backoff, shouldRetry func(err error) bool, f func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	rb := wrap("DoValueIf", "", b, func(ctx context.Context, err error) (time.Duration, error) {
+		if !shouldRetry(err) {
+			recordStop(ctx, StopNonRetryable)
+			return Stop, err
+		}
+		return nextContext(ctx, b, err)
+	})
+
+	var result T
+	err := Do(ctx, rb, func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// DoLatencyRetry wraps a function that produces a value with a backoff to
+// retry. In addition to failed attempts, an attempt that succeeded but took
+// longer than slowerThan is retried as well, hoping for a faster response. In
+// contrast to hedging, the attempts are made sequentially.
+//
+// For a slow attempt, the backoff receives ErrSlowAttempt marked as retryable.
+// Thus the backoff controls how often slow attempts are retried, e.g.
+// WithMaxRetries(1, b) retries a slow attempt once more. As soon as the backoff
+// stops, the result of the fastest successful attempt is returned with a nil
+// error. If no attempt succeeded, the zero value is returned along with the
+// error.
+func DoLatencyRetry[T any](ctx context.Context, b Backoff, slowerThan time.Duration, f func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	var best T
+	var bestLatency time.Duration
+	var succeeded bool
+
+	err := Do(ctx, b, func(ctx context.Context) error {
+		start := timeNow()
+		result, err := f(ctx)
+		latency := timeSince(start)
+		if err != nil {
+			return err
+		}
+
+		if !succeeded || latency < bestLatency {
+			best, bestLatency, succeeded = result, latency, true
+		}
+		if latency > slowerThan {
+			return RetryableError(ErrSlowAttempt)
+		}
+		return nil
+	}, opts...)
+	if succeeded {
+		return best, nil
+	}
+	return best, err
+}
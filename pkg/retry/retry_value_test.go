@@ -0,0 +1,466 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDoValue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("first_try", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		v, err := DoValue(ctx, b, func(_ context.Context) (string, error) {
+			i++
+			return "ok", nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := v, "ok"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := i, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("after_retries", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		v, err := DoValue(ctx, b, func(_ context.Context) (int, error) {
+			i++
+			if i < 3 {
+				return i, fmt.Errorf("oops")
+			}
+			return 42, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := v, 42; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := i, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		b := NewConstant(1 * time.Hour)
+
+		v, err := DoValue(ctx, b, func(_ context.Context) (*int, error) {
+			cancel()
+			n := 42
+			return &n, fmt.Errorf("oops")
+		})
+		if err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if v != nil {
+			t.Errorf("expected %v to be nil", v)
+		}
+	})
+}
+
+func TestDoResultRetry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retry_result", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(NewConstant(1 * time.Nanosecond))
+
+		var i int
+		status, err := DoResultRetry(ctx, b, func(_ context.Context) (string, error) {
+			i++
+			if i < 3 {
+				return "pending", nil
+			}
+			return "done", nil
+		}, func(status string) bool {
+			return status == "pending"
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := status, "done"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := i, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("retry_error", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		n, err := DoResultRetry(ctx, b, func(_ context.Context) (int, error) {
+			i++
+			if i < 3 {
+				return 0, fmt.Errorf("oops")
+			}
+			return 42, nil
+		}, func(int) bool {
+			return false
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 42; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("give_up", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		var i int
+		status, err := DoResultRetry(ctx, b, func(_ context.Context) (string, error) {
+			i++
+			return "pending", nil
+		}, func(status string) bool {
+			return status == "pending"
+		})
+		if !errors.Is(err, ErrRetryResult) {
+			t.Errorf("expected %v to be %v", err, ErrRetryResult)
+		}
+
+		if got, want := status, "pending"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := i, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestDoValueWithCleanup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cleanup_failed", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		var cleaned []int
+		n, err := DoValueWithCleanup(ctx, b, func(_ context.Context) (int, error, func()) {
+			i++
+			attempt := i
+			cleanup := func() { cleaned = append(cleaned, attempt) }
+			if i < 3 {
+				return 0, fmt.Errorf("oops"), cleanup
+			}
+			return 42, nil, cleanup
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 42; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := fmt.Sprint(cleaned), "[1 2]"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("nil_cleanup", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		n, err := DoValueWithCleanup(ctx, b, func(_ context.Context) (int, error, func()) {
+			return 1, fmt.Errorf("oops"), nil
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+		if got, want := n, 0; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var cleaned int
+		_, err := DoValueWithCleanup(ctx, b, func(ctx context.Context) (int, error, func()) {
+			cancel()
+			return 0, ctx.Err(), func() { cleaned++ }
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if got, want := cleaned, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
+
+func TestDoValueIf(t *testing.T) {
+	t.Parallel()
+
+	errTemporary := errors.New("temporary")
+	shouldRetry := func(err error) bool {
+		return errors.Is(err, errTemporary)
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		n, err := DoValueIf(ctx, b, shouldRetry, func(_ context.Context) (int, error) {
+			i++
+			if i < 3 {
+				return 0, errTemporary
+			}
+			return 42, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if got, want := n, 42; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("non_retryable", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		n, err := DoValueIf(ctx, b, shouldRetry, func(_ context.Context) (int, error) {
+			i++
+			if i < 2 {
+				return 0, errTemporary
+			}
+			return 1, io.EOF
+		})
+		if err != io.EOF {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if got, want := n, 0; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := i, 2; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("backoff_stops", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		var i int
+		_, err := DoValueIf(ctx, b, shouldRetry, func(_ context.Context) (int, error) {
+			i++
+			return 0, errTemporary
+		})
+		if err != errTemporary {
+			t.Errorf("expected %v to be %v", err, errTemporary)
+		}
+		if got, want := i, 3; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		b := NewConstant(1 * time.Nanosecond)
+
+		_, err := DoValueIf(ctx, b, shouldRetry, func(_ context.Context) (int, error) {
+			return 1, nil
+		})
+		if err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+}
+
+func ExampleDoValueIf() {
+	ctx := context.Background()
+
+	b := NewFibonacci(1 * time.Nanosecond)
+
+	// This example demonstrates selectively retrying specific errors. Only
+	// server errors are retried.
+	errServer := errors.New("server error")
+	body, err := DoValueIf(ctx, WithMaxRetries(3, b), func(err error) bool {
+		return errors.Is(err, errServer)
+	}, func(ctx context.Context) ([]byte, error) {
+		resp, err := http.Get("https://google.com/")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode / 100 {
+		case 4:
+			return nil, fmt.Errorf("bad response: %v", resp.StatusCode)
+		case 5:
+			return nil, fmt.Errorf("%w: %v", errServer, resp.StatusCode)
+		default:
+			return io.ReadAll(resp.Body)
+		}
+	})
+	if err != nil {
+		// handle error
+	}
+	_ = body
+}
+
+func TestDoLatencyRetry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fast", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+		var i int
+		n, err := DoLatencyRetry(ctx, b, 50*time.Millisecond, func(_ context.Context) (int, error) {
+			i++
+			return i, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("slow_then_fast", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(3, NewConstant(1*time.Nanosecond))
+
+		var i int
+		n, err := DoLatencyRetry(ctx, b, 20*time.Millisecond, func(_ context.Context) (int, error) {
+			i++
+			if i == 1 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return i, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 2; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("fastest_of_slow", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+
+		sleeps := []time.Duration{60 * time.Millisecond, 30 * time.Millisecond, 90 * time.Millisecond}
+		var i int
+		n, err := DoLatencyRetry(ctx, b, 10*time.Millisecond, func(_ context.Context) (int, error) {
+			time.Sleep(sleeps[i])
+			i++
+			return i, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 2; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("slow_then_error", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+
+		var i int
+		n, err := DoLatencyRetry(ctx, b, 10*time.Millisecond, func(_ context.Context) (int, error) {
+			i++
+			if i == 1 {
+				time.Sleep(20 * time.Millisecond)
+				return 1, nil
+			}
+			return 0, fmt.Errorf("oops")
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := n, 1; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+
+		n, err := DoLatencyRetry(ctx, b, 10*time.Millisecond, func(_ context.Context) (int, error) {
+			return 42, fmt.Errorf("oops")
+		})
+		if err == nil {
+			t.Fatal("expected err")
+		}
+
+		if got, want := n, 0; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+}
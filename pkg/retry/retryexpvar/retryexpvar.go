@@ -0,0 +1,116 @@
+// Package retryexpvar publishes statistics about backoffs via expvar.
+//
+// It is a separate package, because importing expvar registers the
+// /debug/vars handler on http.DefaultServeMux as a side effect.
+package retryexpvar
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry"
+)
+
+var mu sync.Mutex
+
+// publish returns the published map with the given name, publishing a new one
+// if necessary.
+func publish(name string) *expvar.Map {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v := expvar.Get(name); v != nil {
+		m, ok := v.(*expvar.Map)
+		if !ok {
+			panic("expvar " + name + " is not a map")
+		}
+		return m
+	}
+	return expvar.NewMap(name)
+}
+
+type expvarBackoff struct {
+	name      string
+	next      retry.Backoff
+	vars      *expvar.Map
+	lastDelay *expvar.Int
+}
+
+// WithExpvar publishes statistics about the backoff via expvar (e.g. served at
+// /debug/vars) under the given name. The published map contains:
+//   - attempts: the number of failed attempts passed to the backoff
+//   - giveups: the number of times the backoff stopped
+//   - last_delay_ns: the most recent delay in nanoseconds
+//
+// Backoffs created with the same name share the published values, so that a
+// backoff can be created for each operation as usual. The values are updated
+// atomically. It panics if the name is already used by a variable that is not
+// a map.
+func WithExpvar(name string, next retry.Backoff) retry.Backoff {
+	vars := publish(name)
+	vars.Add("attempts", 0)
+	vars.Add("giveups", 0)
+	vars.Add("last_delay_ns", 0)
+
+	return &expvarBackoff{
+		name:      name,
+		next:      next,
+		vars:      vars,
+		lastDelay: vars.Get("last_delay_ns").(*expvar.Int),
+	}
+}
+
+// Next implements retry.Backoff.
+func (b *expvarBackoff) Next(err error) (time.Duration, error) {
+	return b.NextContext(context.Background(), err)
+}
+
+// NextContext implements retry.ContextBackoff.
+func (b *expvarBackoff) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	b.vars.Add("attempts", 1)
+
+	var delay time.Duration
+	if cb, ok := b.next.(retry.ContextBackoff); ok {
+		delay, err = cb.NextContext(ctx, err)
+	} else {
+		delay, err = b.next.Next(err)
+	}
+	if retry.IsStopped(delay) {
+		b.vars.Add("giveups", 1)
+		return delay, err
+	}
+
+	b.lastDelay.Set(int64(delay))
+	return delay, err
+}
+
+// Peek implements retry.Peeker. It delegates to the wrapped backoff without
+// recording any statistics.
+func (b *expvarBackoff) Peek(err error) (time.Duration, error) {
+	if p, ok := b.next.(retry.Peeker); ok {
+		return p.Peek(err)
+	}
+	// the adapter of a backoff that does not support peeking reports so in
+	// the same way as the built-in middleware
+	return retry.AsContextBackoff(retry.BackoffFunc(b.next.Next)).(retry.Peeker).Peek(err)
+}
+
+// Reset implements retry.Resettable. It forwards the call to the wrapped
+// backoff, if it is resettable. The published statistics are kept.
+func (b *expvarBackoff) Reset() {
+	if r, ok := b.next.(retry.Resettable); ok {
+		r.Reset()
+	}
+}
+
+// Inner implements retry.Wrapper.
+func (b *expvarBackoff) Inner() retry.Backoff {
+	return b.next
+}
+
+// String returns a description of the backoff.
+func (b *expvarBackoff) String() string {
+	return "WithExpvar(" + b.name + ")"
+}
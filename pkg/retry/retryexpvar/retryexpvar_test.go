@@ -0,0 +1,91 @@
+package retryexpvar
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry"
+)
+
+func TestWithExpvar(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		b := WithExpvar("retryexpvar_test", retry.WithMaxRetries(2, retry.NewConstant(1*time.Nanosecond)))
+		_ = retry.Do(ctx, b, func(_ context.Context) error {
+			return retry.RetryableError(fmt.Errorf("oops"))
+		})
+	}
+
+	vars, ok := expvar.Get("retryexpvar_test").(*expvar.Map)
+	if !ok {
+		t.Fatal("expected map to be published")
+	}
+
+	cases := map[string]int64{
+		"attempts":      6,
+		"giveups":       2,
+		"last_delay_ns": 1,
+	}
+	for key, want := range cases {
+		if got := vars.Get(key).(*expvar.Int).Value(); got != want {
+			t.Errorf("%s: expected %v to be %v", key, got, want)
+		}
+	}
+
+	if got, want := retry.Describe(WithExpvar("retryexpvar_test", retry.NewConstant(1*time.Second))), "WithExpvar(retryexpvar_test) -> Constant(1s)"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestWithExpvarPeekReset(t *testing.T) {
+	t.Parallel()
+
+	b := WithExpvar("retryexpvar_test_peek", retry.NewExponential(1*time.Second))
+	vars := expvar.Get("retryexpvar_test_peek").(*expvar.Map)
+
+	// peeking neither advances the backoff nor records statistics
+	for i := 0; i < 2; i++ {
+		if delay, ok := retry.Peek(b, nil); !ok || delay != 1*time.Second {
+			t.Errorf("expected %v to be %v", delay, 1*time.Second)
+		}
+	}
+	if got := vars.Get("attempts").(*expvar.Int).Value(); got != 0 {
+		t.Errorf("expected %v to be %v", got, 0)
+	}
+
+	// resetting is forwarded to the wrapped backoff
+	b.Next(nil)
+	b.Next(nil)
+	b.(retry.Resettable).Reset()
+	if delay, _ := b.Next(nil); delay != 1*time.Second {
+		t.Errorf("expected %v to be %v", delay, 1*time.Second)
+	}
+
+	// peeking a backoff that does not support it is reported as such
+	f := WithExpvar("retryexpvar_test_peek", retry.BackoffFunc(func(err error) (time.Duration, error) {
+		return 1 * time.Second, err
+	}))
+	if _, ok := retry.Peek(retry.WithMaxRetries(3, f), nil); ok {
+		t.Error("expected peek to be unsupported")
+	}
+}
+
+func ExampleWithExpvar() {
+	ctx := context.Background()
+
+	b := retry.NewExponential(100 * time.Millisecond)
+	b = retry.WithMaxRetries(3, b)
+	b = WithExpvar("retry_fetch", b)
+
+	if err := retry.Do(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
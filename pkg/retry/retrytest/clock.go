@@ -0,0 +1,131 @@
+// Package retrytest provides utilities for testing code that uses the retry
+// package.
+package retrytest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeClock is a retry.Clock, whose time only moves when it is advanced
+// explicitly. Install it using retry.SetClock. It is safe for concurrent use.
+type FakeClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []*sleeper
+
+	// changed is closed and replaced whenever the sleepers change.
+	changed chan struct{}
+}
+
+// sleeper is a pending call of Sleep.
+type sleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+// NewFakeClock returns a fake clock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by d. It returns the error of
+// ctx, if ctx is done before. It returns immediately if d is not greater than
+// 0.
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	s := &sleeper{
+		until: c.now.Add(d),
+		done:  make(chan struct{}),
+	}
+	c.sleepers = append(c.sleepers, s)
+	c.notify()
+	c.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		c.remove(s)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Advance moves the clock forward by d and wakes up all sleepers whose time
+// has come.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, s := range append([]*sleeper(nil), c.sleepers...) {
+		if !s.until.After(c.now) {
+			c.remove(s)
+			close(s.done)
+		}
+	}
+}
+
+// Sleepers returns the number of goroutines currently blocked in Sleep.
+func (c *FakeClock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+// BlockUntil blocks until at least n goroutines are blocked in Sleep. This
+// allows to advance the clock only once the code under test waits for it. It
+// returns the error of ctx, if ctx is done before.
+func (c *FakeClock) BlockUntil(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		count, changed := len(c.sleepers), c.changed
+		c.mu.Unlock()
+		if count >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// remove drops s from the sleepers, if present. The lock must be held.
+func (c *FakeClock) remove(s *sleeper) {
+	for i, o := range c.sleepers {
+		if o == s {
+			c.sleepers = append(c.sleepers[:i], c.sleepers[i+1:]...)
+			c.notify()
+			return
+		}
+	}
+}
+
+// notify signals a change of the sleepers. The lock must be held.
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
@@ -0,0 +1,150 @@
+package retrytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClockSleep(t *testing.T) {
+	t.Parallel()
+
+	c := NewFakeClock(epoch)
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Sleep(ctx, 1*time.Second)
+	}()
+	if err := c.BlockUntil(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// not yet
+	c.Advance(999 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("expected sleep to continue, got %v", err)
+	default:
+	}
+
+	c.Advance(1 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+	if got, want := c.Now(), epoch.Add(1*time.Second); !got.Equal(want) {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+	if got := c.Sleepers(); got != 0 {
+		t.Errorf("expected %v to be 0", got)
+	}
+}
+
+func TestFakeClockSleepZero(t *testing.T) {
+	t.Parallel()
+
+	c := NewFakeClock(epoch)
+	if err := c.Sleep(context.Background(), 0); err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+	if err := c.Sleep(context.Background(), -1*time.Second); err != nil {
+		t.Errorf("expected %v to be nil", err)
+	}
+}
+
+func TestFakeClockSleepCanceled(t *testing.T) {
+	t.Parallel()
+
+	t.Run("before", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewFakeClock(epoch)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := c.Sleep(ctx, 1*time.Second); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if got := c.Sleepers(); got != 0 {
+			t.Errorf("expected %v to be 0", got)
+		}
+	})
+
+	t.Run("during", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewFakeClock(epoch)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Sleep(ctx, 1*time.Second)
+		}()
+		if err := c.BlockUntil(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+		if got := c.Sleepers(); got != 0 {
+			t.Errorf("expected %v to be 0", got)
+		}
+		if got := c.Now(); !got.Equal(epoch) {
+			t.Errorf("expected %v to be %v", got, epoch)
+		}
+	})
+
+	t.Run("block_until", func(t *testing.T) {
+		t.Parallel()
+
+		c := NewFakeClock(epoch)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := c.BlockUntil(ctx, 1); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestFakeClockDo(t *testing.T) {
+	c := NewFakeClock(epoch)
+	retry.SetClock(c)
+	defer retry.SetClock(nil)
+
+	ctx := context.Background()
+	b := retry.WithMaxRetries(3, retry.NewExponential(1*time.Second))
+
+	var elapsed []time.Duration
+	done := make(chan error, 1)
+	go func() {
+		done <- retry.Do(ctx, b, func(_ context.Context) error {
+			elapsed = append(elapsed, c.Now().Sub(epoch))
+			return retry.RetryableError(errors.New("oops"))
+		})
+	}()
+
+	for _, d := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second} {
+		if err := c.BlockUntil(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+		c.Advance(d)
+	}
+	if err := <-done; err == nil {
+		t.Fatal("expected err")
+	}
+
+	exp := []time.Duration{0, 1 * time.Second, 3 * time.Second, 7 * time.Second}
+	if fmt.Sprint(elapsed) != fmt.Sprint(exp) {
+		t.Errorf("expected %v to be %v", elapsed, exp)
+	}
+}
@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type scopeEntry struct {
+	backoff Backoff
+	refs    int
+}
+
+var (
+	scopesMu sync.Mutex
+	scopes   = make(map[string]*scopeEntry)
+)
+
+type scopedBackoff struct {
+	key      string
+	entry    *scopeEntry
+	released uint32
+}
+
+// WithIdempotencyScope returns a backoff shared by all holders of the same
+// idempotency key. The first holder of a key creates the shared backoff using
+// factory; further holders join it until all of them released it (see
+// Release). This way, goroutines racing on the same idempotent write back off
+// together (and share e.g. the deadline of a WithMaxDuration), instead of
+// hammering the server with uncoordinated retries. Different keys are
+// independent.
+//
+// The shared state is reference counted and freed once the last holder
+// released its backoff. Hence every backoff returned by WithIdempotencyScope
+// must be released after use. The shared backoff must be safe for concurrent
+// use.
+func WithIdempotencyScope(key string, factory Factory) Backoff {
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+
+	e, ok := scopes[key]
+	if !ok {
+		e = &scopeEntry{backoff: factory()}
+		scopes[key] = e
+	}
+	e.refs++
+
+	return &scopedBackoff{
+		key:   key,
+		entry: e,
+	}
+}
+
+// Next implements Backoff.
+func (b *scopedBackoff) Next(err error) (time.Duration, error) {
+	return b.NextContext(context.Background(), err)
+}
+
+// NextContext implements ContextBackoff.
+func (b *scopedBackoff) NextContext(ctx context.Context, err error) (time.Duration, error) {
+	return nextContext(ctx, b.entry.backoff, err)
+}
+
+// Peek implements Peeker.
+func (b *scopedBackoff) Peek(err error) (time.Duration, error) {
+	return nextContext(peekContext, b.entry.backoff, err)
+}
+
+// Reset implements Resettable. It resets the shared backoff of the scope, if
+// it is resettable, which affects all holders of the scope.
+func (b *scopedBackoff) Reset() {
+	if r, ok := b.entry.backoff.(Resettable); ok {
+		r.Reset()
+	}
+}
+
+// Inner implements Wrapper.
+func (b *scopedBackoff) Inner() Backoff {
+	return b.entry.backoff
+}
+
+// String returns a description of the backoff.
+func (b *scopedBackoff) String() string {
+	return "WithIdempotencyScope(" + b.key + ")"
+}
+
+// release releases the shared state of the scope. Repeated calls have no
+// effect.
+func (b *scopedBackoff) release() {
+	if !atomic.CompareAndSwapUint32(&b.released, 0, 1) {
+		return
+	}
+
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+
+	b.entry.refs--
+	if b.entry.refs == 0 && scopes[b.key] == b.entry {
+		delete(scopes, b.key)
+	}
+}
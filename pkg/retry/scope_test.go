@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithIdempotencyScope(t *testing.T) {
+	t.Parallel()
+
+	var created int
+	factory := func() Backoff {
+		created++
+		return NewExponential(1 * time.Second)
+	}
+
+	b1 := WithIdempotencyScope("test-scope-a", factory)
+	b2 := WithIdempotencyScope("test-scope-a", factory)
+	other := WithIdempotencyScope("test-scope-b", factory)
+
+	// holders of the same key back off together
+	exp := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	for i, b := range []Backoff{b1, b2, b1} {
+		if delay, _ := b.Next(nil); delay != exp[i] {
+			t.Errorf("call %d: expected %v to be %v", i+1, delay, exp[i])
+		}
+	}
+
+	// different keys are independent
+	if delay, _ := other.Next(nil); delay != 1*time.Second {
+		t.Errorf("expected %v to be %v", delay, 1*time.Second)
+	}
+
+	// the state is kept as long as a holder remains
+	Release(b1)
+	Release(b1)
+	b3 := WithIdempotencyScope("test-scope-a", factory)
+	if delay, _ := b3.Next(nil); delay != 8*time.Second {
+		t.Errorf("expected %v to be %v", delay, 8*time.Second)
+	}
+
+	// and freed once all holders released it
+	Release(b2)
+	Release(b3)
+	b4 := WithIdempotencyScope("test-scope-a", factory)
+	defer Release(b4)
+	if delay, _ := b4.Next(nil); delay != 1*time.Second {
+		t.Errorf("expected %v to be %v", delay, 1*time.Second)
+	}
+
+	Release(other)
+	if got, want := created, 3; got != want {
+		t.Errorf("expected %v to be %v", got, want)
+	}
+}
+
+func ExampleWithIdempotencyScope() {
+	ctx := context.Background()
+
+	// all goroutines writing with the same idempotency key share the backoff
+	b := WithIdempotencyScope("order-42", func() Backoff {
+		return WithMaxDuration(30*time.Second, NewExponential(100*time.Millisecond))
+	})
+	defer Release(b)
+
+	if err := Do(ctx, b, func(_ context.Context) error {
+		// TODO: idempotent write
+		return nil
+	}); err != nil {
+		// handle error
+	}
+}
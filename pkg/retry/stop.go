@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+)
+
+// StopReason is the reason why retrying stopped. See OnStop.
+type StopReason int
+
+const (
+	// StopUnknown is the reason of a stop that was not attributed, e.g. by a
+	// custom backoff.
+	StopUnknown StopReason = iota
+
+	// StopMaxRetries is the reason of a stop due to a limit of retries or
+	// attempts, e.g. WithMaxRetries.
+	StopMaxRetries
+
+	// StopMaxDuration is the reason of a stop due to a limit of the total
+	// duration, e.g. WithMaxDuration, or the deadline of the context (see
+	// WithContextDeadline).
+	StopMaxDuration
+
+	// StopNonRetryable is the reason of a stop due to an error not being
+	// retryable, e.g. WithRetryable.
+	StopNonRetryable
+
+	// StopUnrecoverable is the reason of a stop due to an error marked as
+	// unrecoverable (see Unrecoverable).
+	StopUnrecoverable
+
+	// StopRetryBudget is the reason of a stop due to an exhausted retry
+	// budget (see WithRetryBudget).
+	StopRetryBudget
+
+	// StopRetryLimit is the reason of a stop due to the global retry limit
+	// (see SetGlobalRetryLimit).
+	StopRetryLimit
+
+	// StopCircuitOpen is the reason of a stop due to an open circuit breaker
+	// (see WithErrorRateBreaker).
+	StopCircuitOpen
+)
+
+// String returns the name of the reason.
+func (r StopReason) String() string {
+	switch r {
+	case StopMaxRetries:
+		return "max retries"
+	case StopMaxDuration:
+		return "max duration"
+	case StopNonRetryable:
+		return "non-retryable"
+	case StopUnrecoverable:
+		return "unrecoverable"
+	case StopRetryBudget:
+		return "retry budget"
+	case StopRetryLimit:
+		return "retry limit"
+	case StopCircuitOpen:
+		return "circuit open"
+	default:
+		return "unknown"
+	}
+}
+
+// OnStop configures a function, that is called when retrying stops because
+// the backoff stopped, the retried function returned an unrecoverable error
+// (see Unrecoverable) or the global retry limit was reached (see
+// SetGlobalRetryLimit). It receives the reason of the stop and the error of the
+// last attempt. This allows to tell, e.g., an exhausted WithMaxRetries from an
+// exhausted WithMaxDuration without threading state through the backoff. It is
+// neither called on success nor on the cancellation of the context.
+//
+// The reason is reported by the built-in middleware deciding to stop; stops of
+// other backoffs are reported as StopUnknown.
+func OnStop(fn func(reason StopReason, err error)) Option {
+	return func(c *config) {
+		c.onStop = fn
+	}
+}
+
+// stopped calls the function configured by OnStop, if any.
+func (c *config) stopped(reason StopReason, err error) {
+	if c.onStop != nil {
+		c.onStop(reason, err)
+	}
+}
+
+type stopReasonKey struct{}
+
+// stopReasonRecorder records the reason of a stop decided by a middleware.
+type stopReasonRecorder struct {
+	reason StopReason
+}
+
+// withStopReasonRecorder returns a copy of ctx carrying a recorder for the
+// reason of a stop.
+func withStopReasonRecorder(ctx context.Context) (context.Context, *stopReasonRecorder) {
+	r := &stopReasonRecorder{}
+	return context.WithValue(ctx, stopReasonKey{}, r), r
+}
+
+// recordStop records the reason of a stop decided by a middleware, if the
+// backoff is driven by Do. The first recorded reason wins, i.e. the one of the
+// middleware that decided to stop, rather than the ones passing it on.
+func recordStop(ctx context.Context, reason StopReason) {
+	if r, ok := ctx.Value(stopReasonKey{}).(*stopReasonRecorder); ok && r.reason == StopUnknown {
+		r.reason = reason
+	}
+}
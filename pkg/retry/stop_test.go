@@ -0,0 +1,299 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestOnStop(t *testing.T) {
+	t.Parallel()
+
+	errFatal := errors.New("fatal")
+
+	cases := []struct {
+		name    string
+		backoff func() Backoff
+		err     error
+		reason  StopReason
+	}{
+		{
+			name: "max_retries",
+			backoff: func() Backoff {
+				return WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxRetries,
+		},
+		{
+			name: "max_attempts",
+			backoff: func() Backoff {
+				return WithMaxAttempts(2, NewConstant(1*time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxRetries,
+		},
+		{
+			name: "selective_max_retries",
+			backoff: func() Backoff {
+				return WithSelectiveMaxRetries(2, func(error) bool { return true }, NewConstant(1*time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxRetries,
+		},
+		{
+			name: "max_retries_context_override",
+			backoff: func() Backoff {
+				return WithMaxRetriesContextOverride(2, NewConstant(1*time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxRetries,
+		},
+		{
+			name: "max_duration",
+			backoff: func() Backoff {
+				return WithMaxDuration(10*time.Millisecond, NewConstant(5*time.Millisecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxDuration,
+		},
+		{
+			name: "max_duration_timer",
+			backoff: func() Backoff {
+				return NewMaxDurationTimer(10*time.Millisecond, NewConstant(5*time.Millisecond))
+			},
+			err:    io.EOF,
+			reason: StopMaxDuration,
+		},
+		{
+			name: "non_retryable",
+			backoff: func() Backoff {
+				return WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+			},
+			err:    io.EOF,
+			reason: StopNonRetryable,
+		},
+		{
+			name: "non_timeout",
+			backoff: func() Backoff {
+				return WithRetryableTimeout(NewConstant(1 * time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopNonRetryable,
+		},
+		{
+			name: "unrecoverable",
+			backoff: func() Backoff {
+				return WithMaxRetries(2, NewConstant(1*time.Nanosecond))
+			},
+			err:    Unrecoverable(errFatal),
+			reason: StopUnrecoverable,
+		},
+		{
+			name: "retry_budget",
+			backoff: func() Backoff {
+				return WithRetryBudget(0, 2, NewConstant(1*time.Nanosecond))
+			},
+			err:    io.EOF,
+			reason: StopRetryBudget,
+		},
+		{
+			name: "circuit_open",
+			backoff: func() Backoff {
+				return WithErrorRateBreaker(1*time.Hour, 0, WithMaxRetries(20, NewConstant(1*time.Nanosecond)))
+			},
+			err:    io.EOF,
+			reason: StopCircuitOpen,
+		},
+		{
+			name: "outermost_decides",
+			backoff: func() Backoff {
+				return WithMaxRetries(1, WithMaxDuration(1*time.Hour, NewConstant(1*time.Nanosecond)))
+			},
+			err:    io.EOF,
+			reason: StopMaxRetries,
+		},
+		{
+			name: "unknown",
+			backoff: func() Backoff {
+				return BackoffFunc(func(err error) (time.Duration, error) {
+					return Stop, err
+				})
+			},
+			err:    io.EOF,
+			reason: StopUnknown,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			var reason StopReason
+			var stopErr error
+			err := Do(context.Background(), tc.backoff(), func(_ context.Context) error {
+				return tc.err
+			}, OnStop(func(r StopReason, err error) {
+				calls++
+				reason = r
+				stopErr = err
+			}))
+			if calls != 1 {
+				t.Fatalf("expected 1 call, got %d", calls)
+			}
+			if reason != tc.reason {
+				t.Errorf("expected %v to be %v", reason, tc.reason)
+			}
+			if stopErr != err {
+				t.Errorf("expected %v to be %v", stopErr, err)
+			}
+		})
+	}
+
+	t.Run("not_on_success", func(t *testing.T) {
+		t.Parallel()
+
+		var called bool
+		err := Do(context.Background(), NewConstant(1*time.Nanosecond), func(_ context.Context) error {
+			return nil
+		}, OnStop(func(StopReason, error) {
+			called = true
+		}))
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if called {
+			t.Error("expected no call")
+		}
+	})
+}
+
+func TestOnStopHelpers(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		do     func(ctx context.Context, f RetryFunc, opts ...Option) error
+		reason StopReason
+	}{
+		{
+			name: "DoSummary",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoSummary(ctx, WithMaxRetries(1, NewConstant(1*time.Nanosecond)), f, opts...)
+				return err
+			},
+			reason: StopMaxRetries,
+		},
+		{
+			name: "DoValueIf",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoValueIf(ctx, NewConstant(1*time.Nanosecond), func(error) bool { return false }, func(ctx context.Context) (int, error) {
+					return 0, f(ctx)
+				}, opts...)
+				return err
+			},
+			reason: StopNonRetryable,
+		},
+		{
+			name: "DoResultRetry",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoResultRetry(ctx, WithMaxRetries(1, NewConstant(1*time.Nanosecond)), func(ctx context.Context) (int, error) {
+					return 0, f(ctx)
+				}, func(int) bool { return false }, opts...)
+				return err
+			},
+			reason: StopMaxRetries,
+		},
+		{
+			name: "DoValueWithCleanup",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoValueWithCleanup(ctx, WithMaxRetries(1, NewConstant(1*time.Nanosecond)), func(ctx context.Context) (int, error, func()) {
+					return 0, f(ctx), nil
+				}, opts...)
+				return err
+			},
+			reason: StopMaxRetries,
+		},
+		{
+			name: "DoLatencyRetry",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoLatencyRetry(ctx, WithMaxRetries(1, NewConstant(1*time.Nanosecond)), 1*time.Hour, func(ctx context.Context) (int, error) {
+					return 0, f(ctx)
+				}, opts...)
+				return err
+			},
+			reason: StopMaxRetries,
+		},
+		{
+			name: "DoWorkBudget",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				return DoWorkBudget(ctx, NewConstant(1*time.Nanosecond), 0, f, opts...)
+			},
+			reason: StopMaxDuration,
+		},
+		{
+			name: "DoProgress",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				return DoProgress(ctx, WithMaxRetries(1, NewConstant(1*time.Nanosecond)), func(ctx context.Context) (int64, error) {
+					return 0, f(ctx)
+				}, 0, 10, opts...)
+			},
+			reason: StopMaxRetries,
+		},
+		{
+			name: "DoClassified",
+			do: func(ctx context.Context, f RetryFunc, opts ...Option) error {
+				_, err := DoClassified(ctx, NewConstant(1*time.Nanosecond), func(error) bool { return false }, f, opts...)
+				return err
+			},
+			reason: StopNonRetryable,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var reason StopReason
+			err := tc.do(context.Background(), func(_ context.Context) error {
+				return io.EOF
+			}, OnStop(func(r StopReason, _ error) {
+				reason = r
+			}))
+			if err != io.EOF {
+				t.Errorf("expected %v to be %v", err, io.EOF)
+			}
+			if reason != tc.reason {
+				t.Errorf("expected %v to be %v", reason, tc.reason)
+			}
+		})
+	}
+}
+
+func TestStopReasonString(t *testing.T) {
+	t.Parallel()
+
+	cases := map[StopReason]string{
+		StopUnknown:       "unknown",
+		StopMaxRetries:    "max retries",
+		StopMaxDuration:   "max duration",
+		StopNonRetryable:  "non-retryable",
+		StopUnrecoverable: "unrecoverable",
+		StopRetryBudget:   "retry budget",
+		StopRetryLimit:    "retry limit",
+		StopCircuitOpen:   "circuit open",
+		StopReason(100):   "unknown",
+	}
+	for r, exp := range cases {
+		if got := r.String(); got != exp {
+			t.Errorf("expected %q to be %q", got, exp)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Summary summarizes the attempts of a retried operation. See DoSummary.
+type Summary struct {
+	// Attempts is the number of attempts made.
+	Attempts uint64
+
+	// TotalSleep is the time actually spent waiting between attempts. It may
+	// be less than the sum of the delays, e.g. if the context was canceled
+	// while waiting.
+	TotalSleep time.Duration
+
+	// Elapsed is the total time spent, including the attempts and the waiting
+	// time.
+	Elapsed time.Duration
+
+	// FirstError and LastError are the errors returned by the first and the
+	// last failed attempt. They are nil if no attempt failed.
+	FirstError error
+	LastError  error
+}
+
+// DoSummary is like Do, but additionally returns a summary of the attempts.
+// The summary is returned on success as well as on failure.
+func DoSummary(ctx context.Context, b Backoff, f RetryFunc, opts ...Option) (Summary, error) {
+	var s Summary
+	var sleepStart time.Time
+
+	endSleep := func() {
+		if !sleepStart.IsZero() {
+			s.TotalSleep += timeSince(sleepStart)
+			sleepStart = time.Time{}
+		}
+	}
+
+	sb := wrap("DoSummary", "", b, func(ctx context.Context, err error) (time.Duration, error) {
+		delay, err := nextContext(ctx, b, err)
+		if !IsStopped(delay) && !isPeek(ctx) {
+			sleepStart = timeNow()
+		}
+		return delay, err
+	})
+
+	start := timeNow()
+	err := Do(ctx, sb, func(ctx context.Context) error {
+		endSleep()
+
+		s.Attempts++
+		err := f(ctx)
+		if err != nil {
+			if s.FirstError == nil {
+				s.FirstError = err
+			}
+			s.LastError = err
+		}
+		return err
+	}, opts...)
+
+	// the context may have been canceled while waiting
+	endSleep()
+	s.Elapsed = timeSince(start)
+	return s, err
+}
+
+// DoValueSummary is like DoSummary, but wraps a function that produces a
+// value. On success, the result is returned with a nil error. Otherwise, the
+// zero value of T is returned along with the error, just like with Do. The
+// summary is returned in either case.
+func DoValueSummary[T any](ctx context.Context, b Backoff, f func(ctx context.Context) (T, error), opts ...Option) (T, Summary, error) {
+	var result T
+	s, err := DoSummary(ctx, b, func(ctx context.Context) error {
+		var err error
+		result, err = f(ctx)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, s, err
+	}
+	return result, s, nil
+}
@@ -0,0 +1,141 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDoSummary(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(10 * time.Millisecond)
+
+		var i int
+		s, err := DoSummary(ctx, b, func(_ context.Context) error {
+			i++
+			switch i {
+			case 1:
+				return io.ErrUnexpectedEOF
+			case 2:
+				return io.EOF
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+
+		if got, want := s.Attempts, uint64(3); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := s.FirstError, io.ErrUnexpectedEOF; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if got, want := s.LastError, io.EOF; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if s.TotalSleep < 20*time.Millisecond {
+			t.Errorf("expected %v to be at least %v", s.TotalSleep, 20*time.Millisecond)
+		}
+		if s.Elapsed < s.TotalSleep {
+			t.Errorf("expected %v to be at least %v", s.Elapsed, s.TotalSleep)
+		}
+	})
+
+	t.Run("context_trimmed_sleep", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		b := NewConstant(1 * time.Hour)
+
+		s, err := DoSummary(ctx, b, func(_ context.Context) error {
+			return fmt.Errorf("oops")
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+
+		if got, want := s.Attempts, uint64(1); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if s.TotalSleep < 40*time.Millisecond || s.TotalSleep > 1*time.Second {
+			t.Errorf("expected %v to be about %v", s.TotalSleep, 50*time.Millisecond)
+		}
+	})
+}
+
+func ExampleDoSummary() {
+	ctx := context.Background()
+
+	b := NewExponential(100 * time.Millisecond)
+	b = WithMaxRetries(3, b)
+
+	s, err := DoSummary(ctx, b, func(_ context.Context) error {
+		// TODO: logic here
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("gave up after %d attempts (%v): %v\n", s.Attempts, s.Elapsed, s.LastError)
+	}
+}
+
+func TestDoValueSummary(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := NewConstant(1 * time.Nanosecond)
+
+		var i int
+		v, s, err := DoValueSummary(ctx, b, func(_ context.Context) (int, error) {
+			i++
+			if i < 3 {
+				return i, io.EOF
+			}
+			return 42, nil
+		})
+		if err != nil {
+			t.Fatalf("expected no err, got %v", err)
+		}
+		if v != 42 {
+			t.Errorf("expected %v to be 42", v)
+		}
+		if got, want := s.Attempts, uint64(3); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		b := WithRetryable(WithMaxRetries(2, NewConstant(1*time.Nanosecond)))
+
+		v, s, err := DoValueSummary(ctx, b, func(_ context.Context) (string, error) {
+			return "partial", RetryableError(io.EOF)
+		})
+		if err != io.EOF {
+			t.Errorf("expected %v to be %v", err, io.EOF)
+		}
+		if v != "" {
+			t.Errorf("expected %q to be empty", v)
+		}
+		if got, want := s.Attempts, uint64(3); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		if !errors.Is(s.LastError, io.EOF) {
+			t.Errorf("expected %v to be %v", s.LastError, io.EOF)
+		}
+	})
+}
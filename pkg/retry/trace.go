@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanRecorder records the retries of Do, e.g. as events of the current
+// tracing span. It allows to integrate with a tracing library (such as
+// OpenTelemetry) without depending on it.
+type SpanRecorder interface {
+	// RecordRetry is called before waiting for the next attempt. The attempt is
+	// the 1-based number of the failed attempt, delay the time to wait until
+	// the next one, and err the (processed) error of the failed attempt.
+	RecordRetry(attempt uint64, delay time.Duration, err error)
+}
+
+// SpanRecorderFunc is a SpanRecorder expressed as a function.
+type SpanRecorderFunc func(attempt uint64, delay time.Duration, err error)
+
+// RecordRetry implements SpanRecorder.
+func (f SpanRecorderFunc) RecordRetry(attempt uint64, delay time.Duration, err error) {
+	f(attempt, delay, err)
+}
+
+type spanRecorderKey struct{}
+
+// ContextWithSpanRecorder returns a copy of ctx carrying the SpanRecorder. Do
+// records its retries through it.
+func ContextWithSpanRecorder(ctx context.Context, r SpanRecorder) context.Context {
+	return context.WithValue(ctx, spanRecorderKey{}, r)
+}
+
+var (
+	spanRecorderResolverMu sync.RWMutex
+	spanRecorderResolver   func(ctx context.Context) SpanRecorder
+)
+
+// SetSpanRecorderResolver registers a function that resolves a SpanRecorder
+// from a context, e.g. by looking up the current span of a tracing library. It
+// is consulted by Do if the context does not carry a SpanRecorder explicitly.
+// This way, retries are recorded without further configuration once tracing is
+// set up. The resolver may return nil if there is nothing to record to. A nil
+// function removes the resolver.
+func SetSpanRecorderResolver(fn func(ctx context.Context) SpanRecorder) {
+	spanRecorderResolverMu.Lock()
+	defer spanRecorderResolverMu.Unlock()
+	spanRecorderResolver = fn
+}
+
+// SpanRecorderFromContext returns the SpanRecorder of the context. If the
+// context does not carry one, the registered resolver is consulted (see
+// SetSpanRecorderResolver). It returns nil if there is no SpanRecorder.
+func SpanRecorderFromContext(ctx context.Context) SpanRecorder {
+	if r, ok := ctx.Value(spanRecorderKey{}).(SpanRecorder); ok {
+		return r
+	}
+
+	spanRecorderResolverMu.RLock()
+	resolve := spanRecorderResolver
+	spanRecorderResolverMu.RUnlock()
+	if resolve == nil {
+		return nil
+	}
+	return resolve(ctx)
+}
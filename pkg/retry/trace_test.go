@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type event struct {
+	attempt uint64
+	delay   time.Duration
+	err     string
+}
+
+func TestSpanRecorder(t *testing.T) {
+	t.Parallel()
+
+	var events []event
+	rec := SpanRecorderFunc(func(attempt uint64, delay time.Duration, err error) {
+		events = append(events, event{attempt, delay, err.Error()})
+	})
+	ctx := ContextWithSpanRecorder(context.Background(), rec)
+
+	b := WithRetryable(WithMaxRetries(2, NewExponential(1*time.Nanosecond)))
+
+	var i int
+	if err := Do(ctx, b, func(_ context.Context) error {
+		i++
+		return RetryableError(fmt.Errorf("oops %d", i))
+	}); err == nil {
+		t.Fatal("expected err")
+	}
+
+	// the final failure is not recorded as retry
+	exp := []event{
+		{1, 1 * time.Nanosecond, "oops 1"},
+		{2, 2 * time.Nanosecond, "oops 2"},
+	}
+	if !reflect.DeepEqual(events, exp) {
+		t.Errorf("expected \n\n%v\n\n to be \n\n%v\n\n", events, exp)
+	}
+}
+
+func TestSetSpanRecorderResolver(t *testing.T) {
+	type spanKey struct{}
+
+	var events []string
+	SetSpanRecorderResolver(func(ctx context.Context) SpanRecorder {
+		span, ok := ctx.Value(spanKey{}).(string)
+		if !ok {
+			return nil
+		}
+		return SpanRecorderFunc(func(attempt uint64, _ time.Duration, _ error) {
+			events = append(events, fmt.Sprintf("%s: retry attempt %d", span, attempt))
+		})
+	})
+	defer SetSpanRecorderResolver(nil)
+
+	if r := SpanRecorderFromContext(context.Background()); r != nil {
+		t.Errorf("expected %v to be nil", r)
+	}
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "span")
+	b := WithMaxRetries(1, NewConstant(1*time.Nanosecond))
+	Do(ctx, b, func(_ context.Context) error {
+		return fmt.Errorf("oops")
+	})
+
+	if exp := []string{"span: retry attempt 1"}; !reflect.DeepEqual(events, exp) {
+		t.Errorf("expected %q to be %q", events, exp)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/aisbergg/go-retry/pkg/retrysingle
+
+go 1.21
+
+require (
+	github.com/aisbergg/go-retry v0.0.0-20220730175029-fa1d34185efb
+	golang.org/x/sync v0.7.0
+)
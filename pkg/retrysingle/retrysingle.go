@@ -0,0 +1,130 @@
+// Package retrysingle combines retrying with duplicate call suppression.
+//
+// When many goroutines retry the same operation (identified by a key), only
+// one of them actually executes the retry loop, while the others wait for it
+// and share its result. This reduces the load on a failing dependency, similar
+// to the protection against a cache stampede.
+//
+// The package lives in its own module, so that the retry package stays free of
+// external dependencies.
+package retrysingle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry"
+	"golang.org/x/sync/singleflight"
+)
+
+// Func is a function passed to Do. Its result is shared by all callers with the
+// same key.
+type Func func(ctx context.Context) (any, error)
+
+// Group suppresses duplicate retries of operations with the same key. The zero
+// value is ready to use.
+type Group struct {
+	// Timeout bounds each shared retry loop, if greater than 0. Since a shared
+	// retry loop is detached from the contexts of its callers, this is the
+	// only deadline it has.
+	Timeout time.Duration
+
+	g singleflight.Group
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call is the shared retry loop of a key.
+type call struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// DefaultGroup is the Group used by Do.
+var DefaultGroup = &Group{}
+
+// Do retries f with the backoff b, unless a retry of an operation with the same
+// key is already in flight. In that case it waits for the one in flight to
+// complete and returns its result instead. The backoff of the caller that
+// started the execution is used for retrying.
+//
+// The shared retry loop is detached from the contexts of the callers: it keeps
+// the values of the context of the caller that started it, but is not
+// canceled along with it. A caller stops waiting when its own context is done,
+// and the retry loop is canceled once no caller waits for it anymore, or when
+// the Timeout of the group has passed.
+func (g *Group) Do(ctx context.Context, key string, b retry.Backoff, f Func) (any, error) {
+	c := g.join(ctx, key)
+	defer g.leave(key, c)
+
+	ch := g.g.DoChan(key, func() (any, error) {
+		var v any
+		err := retry.Do(c.ctx, b, func(ctx context.Context) error {
+			var err error
+			v, err = f(ctx)
+			return err
+		})
+		return v, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
+
+// join registers a caller waiting for the shared retry loop of key, preparing
+// its context if necessary.
+func (g *Group) join(ctx context.Context, key string) *call {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call{}
+		c.ctx = context.WithoutCancel(ctx)
+		if g.Timeout > 0 {
+			c.ctx, c.cancel = context.WithTimeout(c.ctx, g.Timeout)
+		} else {
+			c.ctx, c.cancel = context.WithCancel(c.ctx)
+		}
+		g.calls[key] = c
+	}
+	c.waiters++
+	return c
+}
+
+// leave unregisters a caller joined by join. The shared retry loop is canceled
+// once the last caller has left. The canceled loop may still be running for a
+// while, so the key is forgotten as well: a caller arriving in the meantime
+// starts a new retry loop instead of waiting for the canceled one.
+func (g *Group) leave(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		delete(g.calls, key)
+		g.g.Forget(key)
+	}
+}
+
+// Forget tells the group to forget about a key. Future calls to Do for this key
+// start a new retry rather than waiting for an earlier one to complete.
+func (g *Group) Forget(key string) {
+	g.g.Forget(key)
+}
+
+// Do retries f using the DefaultGroup. See Group.Do.
+func Do(ctx context.Context, key string, b retry.Backoff, f Func) (any, error) {
+	return DefaultGroup.Do(ctx, key, b, f)
+}
@@ -0,0 +1,244 @@
+package retrysingle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aisbergg/go-retry/pkg/retry"
+)
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shared", func(t *testing.T) {
+		t.Parallel()
+
+		var g Group
+		ctx := context.Background()
+
+		var calls int32
+		release := make(chan struct{})
+		f := func(_ context.Context) (any, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-release
+				return nil, fmt.Errorf("oops")
+			}
+			return "hello", nil
+		}
+
+		const n = 10
+		var wg sync.WaitGroup
+		results := make([]any, n)
+		errs := make([]error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = g.Do(ctx, "key", retry.NewConstant(1*time.Millisecond), f)
+			}(i)
+		}
+
+		// give all goroutines the chance to join the call in flight
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if got, want := atomic.LoadInt32(&calls), int32(2); got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+		for i := 0; i < n; i++ {
+			if errs[i] != nil {
+				t.Errorf("expected no err, got %v", errs[i])
+			}
+			if got, want := results[i], "hello"; got != want {
+				t.Errorf("expected %v to be %v", got, want)
+			}
+		}
+	})
+
+	t.Run("context_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		var g Group
+		release := make(chan struct{})
+		defer close(release)
+
+		go g.Do(context.Background(), "key", retry.NewConstant(1*time.Millisecond), func(_ context.Context) (any, error) {
+			<-release
+			return nil, nil
+		})
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := g.Do(ctx, "key", retry.NewConstant(1*time.Millisecond), func(_ context.Context) (any, error) {
+			t.Error("should not be called")
+			return nil, nil
+		})
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("leader_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		var g Group
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		// the caller starting the retry loop gives up early
+		leaderCtx, cancel := context.WithCancel(context.Background())
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := g.Do(leaderCtx, "key", retry.NewConstant(1*time.Millisecond), func(ctx context.Context) (any, error) {
+				close(started)
+				select {
+				case <-release:
+					return "hello", nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			})
+			leaderErr <- err
+		}()
+		<-started
+
+		waiterResult := make(chan any, 1)
+		waiterErr := make(chan error, 1)
+		go func() {
+			v, err := g.Do(context.Background(), "key", retry.NewConstant(1*time.Millisecond), func(_ context.Context) (any, error) {
+				t.Error("should not be called")
+				return nil, nil
+			})
+			waiterResult <- v
+			waiterErr <- err
+		}()
+
+		// give the waiter the chance to join the call in flight
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		if err := <-leaderErr; err != context.Canceled {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+
+		// the waiter still gets the result of the shared retry loop
+		close(release)
+		if err := <-waiterErr; err != nil {
+			t.Errorf("expected no err, got %v", err)
+		}
+		if got, want := <-waiterResult, "hello"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("all_left", func(t *testing.T) {
+		t.Parallel()
+
+		var g Group
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+
+		go g.Do(ctx, "key", retry.NewConstant(1*time.Millisecond), func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			done <- ctx.Err()
+			return nil, ctx.Err()
+		})
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		// the retry loop is canceled once nobody waits for it anymore
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Errorf("expected %v to be %v", err, context.Canceled)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatal("expected the retry loop to be canceled")
+		}
+	})
+
+	t.Run("all_left_new_caller", func(t *testing.T) {
+		t.Parallel()
+
+		var g Group
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		// the first retry loop ignores the cancellation and keeps running
+		go g.Do(ctx, "key", retry.NewConstant(1*time.Millisecond), func(_ context.Context) (any, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		<-started
+		cancel()
+
+		// wait for the first caller to leave
+		for {
+			g.mu.Lock()
+			n := len(g.calls)
+			g.mu.Unlock()
+			if n == 0 {
+				break
+			}
+			time.Sleep(1 * time.Millisecond)
+		}
+
+		// a new caller starts a new retry loop instead of waiting for the
+		// canceled one
+		ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		v, err := g.Do(ctx, "key", retry.NewConstant(1*time.Millisecond), func(ctx context.Context) (any, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return "fresh", nil
+		})
+		if err != nil {
+			t.Errorf("expected no err, got %v", err)
+		}
+		if got, want := v, "fresh"; got != want {
+			t.Errorf("expected %v to be %v", got, want)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		g := Group{Timeout: 10 * time.Millisecond}
+		_, err := g.Do(context.Background(), "key", retry.NewConstant(1*time.Millisecond), func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected %v to be %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func ExampleDo() {
+	ctx := context.Background()
+
+	b := retry.WithMaxRetries(3, retry.NewExponential(100*time.Millisecond))
+
+	// concurrent calls with the same key share a single retry loop
+	v, err := Do(ctx, "user/42", b, func(ctx context.Context) (any, error) {
+		// TODO: fetch the user
+		return "user 42", nil
+	})
+	if err != nil {
+		// handle error
+	}
+
+	fmt.Println(v)
+	// Output:
+	// user 42
+}